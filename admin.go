@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+)
+
+// adminToken is the shared secret required by the admin endpoints, sent in
+// the X-Admin-Token header. It is read once from BLOG_ADMIN_TOKEN at startup;
+// the admin endpoints reject every request when it is unset.
+var adminToken = os.Getenv("BLOG_ADMIN_TOKEN")
+
+// validAdminToken reports whether token matches adminToken, comparing in
+// constant time so the secret cannot be guessed from response times.
+func validAdminToken(token string) bool {
+	if adminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// reloadContent drops the cached posts and templates and renders every post
+// again, returning the number of posts loaded across all sections.
+func reloadContent() (int, error) {
+	postCache.Clear()
+	contentVersion.Add(1)
+	if err := ReloadTemplates(); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, section := range []Section{postsSection, notesSection} {
+		posts, err := loadAllPosts(section)
+		if err != nil {
+			return 0, err
+		}
+		total += len(posts)
+	}
+	return total, nil
+}
+
+// AdminReloadHandler serves POST /admin/reload, which clears the content
+// caches without a restart and reports how many posts were reloaded.
+func AdminReloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !validAdminToken(r.Header.Get("X-Admin-Token")) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	n, err := reloadContent()
+	if err != nil {
+		logRequestError(r, err)
+		http.Error(w, "Error reloading content", http.StatusInternalServerError)
+		return
+	}
+	logInfo("Reloaded %d posts", n)
+
+	writeJSON(w, http.StatusOK, struct {
+		Posts int `json:"posts"`
+	}{n})
+}
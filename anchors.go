@@ -0,0 +1,87 @@
+package main
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// The anchors extension appends an <a class="anchor"> permalink to h2–h4
+// headings so readers can copy a deep link. It relies on the ids assigned by
+// the parser's auto heading ID option, which are already unique within a
+// document.
+
+// KindHeadingAnchor is the node kind of a heading permalink.
+var KindHeadingAnchor = ast.NewNodeKind("HeadingAnchor")
+
+// HeadingAnchor is a permalink to the heading it is appended to. It has no
+// children, so it adds nothing to the heading's text.
+type HeadingAnchor struct {
+	ast.BaseInline
+	ID []byte
+}
+
+func (n *HeadingAnchor) Kind() ast.NodeKind { return KindHeadingAnchor }
+
+func (n *HeadingAnchor) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"ID": string(n.ID)}, nil)
+}
+
+// anchorLevels are the heading levels that get a permalink.
+const (
+	minAnchorLevel = 2
+	maxAnchorLevel = 4
+)
+
+type anchorTransformer struct{}
+
+func (t *anchorTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		if heading.Level < minAnchorLevel || heading.Level > maxAnchorLevel {
+			return ast.WalkSkipChildren, nil
+		}
+		if id, ok := heading.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				heading.AppendChild(heading, &HeadingAnchor{ID: b})
+			}
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+type anchorRenderer struct{}
+
+func (r *anchorRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindHeadingAnchor, r.renderAnchor)
+}
+
+func (r *anchorRenderer) renderAnchor(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	w.WriteString(` <a class="anchor" href="#`)
+	w.Write(util.EscapeHTML(n.(*HeadingAnchor).ID))
+	w.WriteString(`" aria-label="Permalink">#</a>`)
+	return ast.WalkSkipChildren, nil
+}
+
+type anchorExtension struct{}
+
+// anchorExt adds heading permalinks to a goldmark converter.
+var anchorExt = &anchorExtension{}
+
+func (e *anchorExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(&anchorTransformer{}, 500)),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(util.Prioritized(&anchorRenderer{}, 500)),
+	)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiPost is the JSON representation of a post in API listings.
+type apiPost struct {
+	Title   string   `json:"title"`
+	Slug    string   `json:"slug"`
+	Author  string   `json:"author"`
+	Date    string   `json:"date"`
+	Tags    []string `json:"tags"`
+	Summary string   `json:"summary"`
+}
+
+// apiPostDetail is the JSON representation of a single post.
+type apiPostDetail struct {
+	Title   string   `json:"title"`
+	Slug    string   `json:"slug"`
+	Author  string   `json:"author"`
+	Date    string   `json:"date"`
+	Tags    []string `json:"tags"`
+	Content string   `json:"content"`
+}
+
+// newAPIPost converts a post to its API representation.
+func newAPIPost(post PostData) apiPost {
+	tags := post.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return apiPost{
+		Title:   post.Title,
+		Slug:    post.Slug,
+		Author:  post.Author,
+		Date:    post.Date.Format(time.RFC3339),
+		Tags:    tags,
+		Summary: string(post.Summary),
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError writes an API error response of the form {"error": msg}.
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// APIPostsHandler returns the published posts as JSON in listing order, as
+// sortPosts leaves them: weighted posts first, then the rest newest first.
+// The list can be filtered with ?tag= and capped with ?limit=.
+func APIPostsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := -1
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "error loading posts")
+		return
+	}
+
+	tag := query.Get("tag")
+	out := []apiPost{}
+	for _, post := range posts {
+		if limit >= 0 && len(out) == limit {
+			break
+		}
+		if tag != "" && !hasTag(post, tag) {
+			continue
+		}
+		out = append(out, newAPIPost(post))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// APIPostHandler returns a single post, including its rendered HTML, as JSON.
+func APIPostHandler(w http.ResponseWriter, r *http.Request) {
+	slug := strings.TrimPrefix(r.URL.Path, "/api/post/")
+	// LoadPost validates the slug the same way as for the HTML page
+	post, err := LoadPost(slug)
+	if errors.Is(err, fs.ErrNotExist) {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		logRequestError(r, err)
+		writeJSONError(w, http.StatusInternalServerError, "error loading post")
+		return
+	}
+
+	listed := newAPIPost(post)
+	writeJSON(w, http.StatusOK, apiPostDetail{
+		Title:   listed.Title,
+		Slug:    listed.Slug,
+		Author:  listed.Author,
+		Date:    listed.Date,
+		Tags:    listed.Tags,
+		Content: string(post.Content),
+	})
+}
+
+// apiTag is the JSON representation of a tag and its post count.
+type apiTag struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// APITagsHandler returns every tag of the published posts with its post
+// count, most used first and alphabetically among equal counts.
+func APITagsHandler(w http.ResponseWriter, r *http.Request) {
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "error loading posts")
+		return
+	}
+
+	// countTags sorts by name, so a stable sort by count keeps ties alphabetical
+	tags := countTags(posts)
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].Count > tags[j].Count
+	})
+
+	out := make([]apiTag, 0, len(tags))
+	for _, tag := range tags {
+		out = append(out, apiTag{Tag: tag.Name, Count: tag.Count})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// apiStats is the JSON representation of the site statistics.
+type apiStats struct {
+	Posts     int            `json:"posts"`
+	Drafts    int            `json:"drafts"`
+	Scheduled int            `json:"scheduled"`
+	Tags      int            `json:"tags"`
+	Words     int            `json:"words"`
+	Newest    string         `json:"newest,omitempty"`
+	Oldest    string         `json:"oldest,omitempty"`
+	Views     map[string]int `json:"views"`
+}
+
+// statsCacheTTL bounds how long computed statistics are reused, so a burst of
+// requests does not load every post each time.
+const statsCacheTTL = 30 * time.Second
+
+// cachedStats is a computed apiStats without its view counts.
+type cachedStats struct {
+	stats   apiStats
+	version uint64 // contentVersion when the stats were computed
+	created time.Time
+}
+
+var (
+	statsCacheMu sync.Mutex
+	statsCache   = make(map[bool]cachedStats) // Keyed by whether drafts count
+)
+
+// computeStats returns the statistics of the blog posts. Posts, tags, words
+// and dates cover the published posts, and the drafts and scheduled posts too
+// when withDrafts is set; Drafts and Scheduled always count them.
+func computeStats(withDrafts bool) (apiStats, error) {
+	all, err := loadAllPosts(postsSection)
+	if err != nil {
+		return apiStats{}, err
+	}
+
+	var stats apiStats
+	var counted []PostData
+	for _, post := range all {
+		if post.Draft || post.Scheduled() {
+			if post.Draft {
+				stats.Drafts++
+			} else {
+				stats.Scheduled++
+			}
+			if !withDrafts {
+				continue
+			}
+		}
+		counted = append(counted, post)
+	}
+
+	stats.Posts = len(counted)
+	stats.Tags = len(countTags(counted))
+	var newest, oldest time.Time
+	for _, post := range counted {
+		stats.Words += post.WordCount
+		if newest.IsZero() || post.Date.After(newest) {
+			newest = post.Date
+		}
+		if oldest.IsZero() || post.Date.Before(oldest) {
+			oldest = post.Date
+		}
+	}
+	if len(counted) > 0 {
+		stats.Newest = newest.Format(time.RFC3339)
+		stats.Oldest = oldest.Format(time.RFC3339)
+	}
+	return stats, nil
+}
+
+// siteStats returns computeStats(withDrafts), reusing the last result until
+// it is older than statsCacheTTL or the content changes.
+func siteStats(withDrafts bool) (apiStats, error) {
+	version := contentVersion.Load()
+	statsCacheMu.Lock()
+	entry, ok := statsCache[withDrafts]
+	statsCacheMu.Unlock()
+	if ok && entry.version == version && time.Since(entry.created) <= statsCacheTTL {
+		return entry.stats, nil
+	}
+
+	stats, err := computeStats(withDrafts)
+	if err != nil {
+		return apiStats{}, err
+	}
+	statsCacheMu.Lock()
+	statsCache[withDrafts] = cachedStats{stats: stats, version: version, created: time.Now()}
+	statsCacheMu.Unlock()
+	return stats, nil
+}
+
+// APIStatsHandler returns an overview of the blog: post, draft and tag counts,
+// total words, the newest and oldest post dates, and the view count of every
+// viewed post keyed by section and slug. ?drafts=true counts drafts and
+// scheduled posts as posts.
+func APIStatsHandler(w http.ResponseWriter, r *http.Request) {
+	withDrafts := false
+	if value := r.URL.Query().Get("drafts"); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid drafts")
+			return
+		}
+		withDrafts = b
+	}
+
+	stats, err := siteStats(withDrafts)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "error loading posts")
+		return
+	}
+	stats.Views = views.Counts()
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// ArchiveYear groups the posts published in one year by month.
+type ArchiveYear struct {
+	Year   int
+	Months []ArchiveMonth
+}
+
+// ArchiveMonth holds the posts published in one month.
+type ArchiveMonth struct {
+	Month time.Month
+	Posts []PostData
+}
+
+// buildArchive groups posts by year and month. The posts must already be
+// sorted newest first, which keeps years, months, and posts in that order.
+func buildArchive(posts []PostData) []ArchiveYear {
+	var years []ArchiveYear
+	for _, post := range posts {
+		year, month := post.Date.Year(), post.Date.Month()
+		if len(years) == 0 || years[len(years)-1].Year != year {
+			years = append(years, ArchiveYear{Year: year})
+		}
+		y := &years[len(years)-1]
+		if len(y.Months) == 0 || y.Months[len(y.Months)-1].Month != month {
+			y.Months = append(y.Months, ArchiveMonth{Month: month})
+		}
+		m := &y.Months[len(y.Months)-1]
+		m.Posts = append(m.Posts, post)
+	}
+	return years
+}
+
+// ArchiveHandler renders all posts grouped by year and month.
+func ArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+	posts = newestFirst(posts)
+
+	data := struct {
+		Title     string
+		Canonical string
+		Theme     string
+		Years     []ArchiveYear
+	}{
+		Title:     "Archive",
+		Canonical: canonicalURL(r),
+		Theme:     themeFor(r),
+		Years:     buildArchive(posts),
+	}
+
+	if err := renderTemplate(w, "archive", data); err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultAuthor is credited for posts without an author when neither
+// BLOG_AUTHOR nor the config file names one.
+const defaultAuthor = "Mehmet Ali Baykara"
+
+// siteAuthor returns the author credited for posts that do not name one in
+// their frontmatter. It is read from BLOG_AUTHOR, falling back to author in
+// the config file.
+func siteAuthor() string {
+	if author := os.Getenv("BLOG_AUTHOR"); author != "" {
+		return author
+	}
+	return config.Author
+}
+
+// authorNames returns every distinct author across the posts sorted by name.
+// Names that differ only in case are treated as the same author.
+func authorNames(posts []PostData) []string {
+	seen := make(map[string]bool)
+	var authors []string
+	for _, post := range posts {
+		key := strings.ToLower(post.Author)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		authors = append(authors, post.Author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		return strings.ToLower(authors[i]) < strings.ToLower(authors[j])
+	})
+	return authors
+}
+
+// AuthorHandler renders the posts written by the author named in the path.
+func AuthorHandler(w http.ResponseWriter, r *http.Request) {
+	author := strings.TrimPrefix(r.URL.Path, "/authors/")
+	if author == "" {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+
+	var written []PostData
+	for _, post := range posts {
+		if strings.EqualFold(post.Author, author) {
+			written = append(written, post)
+		}
+	}
+	if len(written) == 0 {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	data := newListingData(r, "Posts by "+written[0].Author, written)
+
+	if err := renderTemplate(w, "author", data); err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+}
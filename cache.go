@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cachedPost is a rendered post along with the ModTime of its source file.
+type cachedPost struct {
+	modTime time.Time
+	post    PostData
+}
+
+// PostCache keeps rendered posts in memory, keyed by file path, so that a
+// file is only read and converted again once its ModTime changes.
+type PostCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedPost
+}
+
+// NewPostCache returns an empty PostCache.
+func NewPostCache() *PostCache {
+	return &PostCache{entries: make(map[string]cachedPost)}
+}
+
+// postCache is shared by LoadBlogPosts and LoadPost.
+var postCache = NewPostCache()
+
+// contentVersion is incremented whenever the content watcher sees a change,
+// so caches built from many posts, like the feeds, know to rebuild.
+var contentVersion atomic.Uint64
+
+// Load returns the post stored in file, rendering it only when it is not
+// cached yet or the file has been modified since it was cached.
+func (c *PostCache) Load(file string) (PostData, error) {
+	info, err := fs.Stat(contentFS(), file)
+	if err != nil {
+		return PostData{}, err
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[file]
+	c.mu.RUnlock()
+	if ok && entry.modTime.Equal(info.ModTime()) {
+		return entry.post, nil
+	}
+
+	post, err := loadPostFile(file)
+	if err != nil {
+		return PostData{}, err
+	}
+
+	c.mu.Lock()
+	c.entries[file] = cachedPost{modTime: info.ModTime(), post: post}
+	c.mu.Unlock()
+	return post, nil
+}
+
+// Evict removes the entry for file, so the next Load renders it again.
+func (c *PostCache) Evict(file string) {
+	c.mu.Lock()
+	delete(c.entries, file)
+	c.mu.Unlock()
+}
+
+// Clear removes every entry, so each post is rendered again on its next Load.
+func (c *PostCache) Clear() {
+	c.mu.Lock()
+	c.entries = make(map[string]cachedPost)
+	c.mu.Unlock()
+}
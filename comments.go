@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Comment providers supported by the comments config.
+const (
+	giscusComments = "giscus"
+	disqusComments = "disqus"
+)
+
+// CommentsConfig selects the third-party comment system embedded below posts.
+// Comments are off when Provider is empty.
+type CommentsConfig struct {
+	Provider string `yaml:"provider"` // "giscus" or "disqus"
+	// ID is the giscus repository as owner/name, or the Disqus shortname.
+	ID string `yaml:"id"`
+	// RepoID, Category and CategoryID are the giscus settings shown on
+	// https://giscus.app for the repository.
+	RepoID     string `yaml:"repo_id"`
+	Category   string `yaml:"category"`
+	CategoryID string `yaml:"category_id"`
+}
+
+var (
+	giscusRepo      = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+	disqusShortname = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+)
+
+// validate reports missing or malformed settings of the chosen provider.
+func (c CommentsConfig) validate() error {
+	switch c.Provider {
+	case "":
+		return nil
+	case giscusComments:
+		if !giscusRepo.MatchString(c.ID) {
+			return fmt.Errorf("comments id %q is not a giscus repository of the form owner/name", c.ID)
+		}
+		if c.RepoID == "" || c.CategoryID == "" {
+			return fmt.Errorf("giscus comments need repo_id and category_id")
+		}
+	case disqusComments:
+		if !disqusShortname.MatchString(c.ID) {
+			return fmt.Errorf("comments id %q is not a Disqus shortname", c.ID)
+		}
+	default:
+		return fmt.Errorf("unknown comments provider %q, want %q or %q", c.Provider, giscusComments, disqusComments)
+	}
+	return nil
+}
+
+// cspSources are the sources of a CSP directive.
+type cspSources struct {
+	directive string
+	sources   string
+}
+
+// commentSources lists, per provider, where its embed loads from.
+var commentSources = map[string][]cspSources{
+	giscusComments: {
+		{"script-src", "https://giscus.app"},
+		{"frame-src", "https://giscus.app"},
+	},
+	disqusComments: {
+		{"script-src", "https://*.disqus.com https://*.disquscdn.com"},
+		{"style-src", "https://*.disquscdn.com"},
+		{"frame-src", "https://disqus.com https://*.disqus.com"},
+		{"connect-src", "https://*.disqus.com"},
+	},
+}
+
+// allowComments returns policy with the sources of the comments provider
+// added, so its embed is not blocked. Directives missing from policy are
+// added with 'self' and the provider's sources.
+func allowComments(policy, provider string) string {
+	directives := strings.Split(policy, "; ")
+	for _, add := range commentSources[provider] {
+		found := false
+		for i, d := range directives {
+			if d == add.directive || strings.HasPrefix(d, add.directive+" ") {
+				directives[i] = d + " " + add.sources
+				found = true
+			}
+		}
+		if !found {
+			directives = append(directives, add.directive+" 'self' "+add.sources)
+		}
+	}
+	return strings.Join(directives, "; ")
+}
+
+// postComments returns the comments config for post, or nil when comments
+// are off for the site or the post's frontmatter sets comments: false.
+func postComments(post PostData) *CommentsConfig {
+	if config.Comments.Provider == "" || post.NoComments {
+		return nil
+	}
+	c := config.Comments
+	return &c
+}
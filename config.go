@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config holds the site-wide settings read from the config file.
+type Config struct {
+	SiteTitle   string `yaml:"site_title"`
+	BaseURL     string `yaml:"base_url"`
+	Description string `yaml:"description"`
+	Author      string `yaml:"author"`
+	Favicon     string `yaml:"favicon"`     // Path of the favicon in the site directory
+	ThemeColor  string `yaml:"theme_color"` // Browser UI color, also used by the web manifest
+	Image       string `yaml:"image"`       // Link preview image of posts without one
+	// Copyright names the holder in the footer, the site title when empty.
+	// StartYear is the first year of the copyright range, the current year
+	// when unset.
+	Copyright string `yaml:"copyright"`
+	StartYear int    `yaml:"start_year"`
+	// ContentDir is the directory holding posts/, notes/, and nav/. Empty
+	// means the working directory, or the embedded files with -tags embed.
+	ContentDir string `yaml:"content_dir"`
+	// ContentRepo is the URL of a Git repository cloned into the content
+	// directory at startup and fetched again periodically. ContentBranch
+	// selects its branch, the remote's default when empty.
+	ContentRepo   string `yaml:"content_repo"`
+	ContentBranch string `yaml:"content_branch"`
+	// PostPatterns are the file name patterns, in path.Match syntax, of the
+	// Markdown files in posts/ and notes/.
+	PostPatterns []string `yaml:"post_patterns"`
+	// Menu lists the nav links in order. Empty means the built-in menu.
+	Menu []MenuItem `yaml:"menu"`
+
+	// Permalinks selects the URL scheme of blog posts: "slug" for
+	// /post/<slug>, or "dated" for /YYYY/MM/<slug>.
+	Permalinks string `yaml:"permalinks"`
+	// RedirectPostURLs makes /post/<slug> redirect to the dated URL instead
+	// of serving the post under both, when Permalinks is "dated".
+	RedirectPostURLs bool `yaml:"redirect_post_urls"`
+	// ExternalLinksNewTab opens links to other sites in a new tab, with
+	// rel="noopener noreferrer".
+	ExternalLinksNewTab bool `yaml:"external_links_new_tab"`
+	// TrailingSlash is "strip" to redirect /post/my-post/ to /post/my-post,
+	// or "add" to redirect the other way.
+	TrailingSlash string `yaml:"trailing_slash"`
+	// Comments embeds a third-party comment system below posts.
+	Comments CommentsConfig `yaml:"comments"`
+}
+
+// defaultConfigFile is the config file read when -config is not set.
+const defaultConfigFile = "config.yaml"
+
+// config is the active site configuration. It holds the defaults until main
+// replaces it with the result of LoadConfig.
+var config = defaultConfig()
+
+// defaultConfig returns the settings used for keys missing from the config
+// file, or for everything when there is no config file.
+func defaultConfig() Config {
+	return Config{
+		SiteTitle:     "Infrastructure Blog",
+		BaseURL:       "http://localhost:8090",
+		Description:   "Weniger aber Besser",
+		Author:        defaultAuthor,
+		Favicon:       "static/favicon.ico",
+		ThemeColor:    "#1d1f21",
+		Permalinks:    slugPermalinks,
+		TrailingSlash: stripTrailingSlash,
+		PostPatterns:  []string{"*.md"},
+	}
+}
+
+// LoadConfig reads the YAML config file at path on top of the defaults. A
+// missing file is not an error and yields the defaults.
+func LoadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	var file Config
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return cfg, err
+	}
+	if file.SiteTitle != "" {
+		cfg.SiteTitle = file.SiteTitle
+	}
+	if file.BaseURL != "" {
+		cfg.BaseURL = file.BaseURL
+	}
+	if file.Description != "" {
+		cfg.Description = file.Description
+	}
+	if file.Author != "" {
+		cfg.Author = file.Author
+	}
+	if file.Favicon != "" {
+		cfg.Favicon = file.Favicon
+	}
+	if file.ThemeColor != "" {
+		cfg.ThemeColor = file.ThemeColor
+	}
+	cfg.Image = file.Image
+	cfg.Copyright = file.Copyright
+	cfg.StartYear = file.StartYear
+	cfg.ContentDir = file.ContentDir
+	cfg.ContentRepo = file.ContentRepo
+	cfg.ContentBranch = file.ContentBranch
+	if len(file.PostPatterns) > 0 {
+		if err := checkPostPatterns(file.PostPatterns); err != nil {
+			return cfg, err
+		}
+		cfg.PostPatterns = file.PostPatterns
+	}
+	cfg.Menu = file.Menu
+	if file.Permalinks != "" {
+		if file.Permalinks != slugPermalinks && file.Permalinks != datedPermalinks {
+			return cfg, fmt.Errorf("unknown permalinks %q, want %q or %q", file.Permalinks, slugPermalinks, datedPermalinks)
+		}
+		cfg.Permalinks = file.Permalinks
+	}
+	cfg.RedirectPostURLs = file.RedirectPostURLs
+	cfg.ExternalLinksNewTab = file.ExternalLinksNewTab
+	if file.TrailingSlash != "" {
+		if file.TrailingSlash != stripTrailingSlash && file.TrailingSlash != addTrailingSlash {
+			return cfg, fmt.Errorf("unknown trailing_slash %q, want %q or %q", file.TrailingSlash, stripTrailingSlash, addTrailingSlash)
+		}
+		cfg.TrailingSlash = file.TrailingSlash
+	}
+	if err := file.Comments.validate(); err != nil {
+		return cfg, err
+	}
+	cfg.Comments = file.Comments
+	return cfg, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// siteFS holds the templates, content, and static files. It reads from the
+// working directory, so edits show up without rebuilding, unless the binary
+// was built with -tags embed (see embed.go). Content is read from contentDir
+// instead when one is set.
+var siteFS fs.FS = os.DirFS(".")
+
+// contentDir is the directory posts, notes, and nav pages are read from. When
+// empty they come from siteFS along with the templates and static files.
+var contentDir string
+
+// contentFS returns the file system holding posts, notes, and nav pages.
+func contentFS() fs.FS {
+	if contentDir == "" {
+		return siteFS
+	}
+	return os.DirFS(contentDir)
+}
+
+// resolveContentDir returns the content directory, read from
+// BLOG_CONTENT_DIR, falling back to content_dir in the config file.
+func resolveContentDir() string {
+	if dir := os.Getenv("BLOG_CONTENT_DIR"); dir != "" {
+		return dir
+	}
+	return config.ContentDir
+}
+
+// checkPostPatterns reports the first malformed pattern in patterns.
+func checkPostPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("post pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// isPostFile reports whether the base name of file matches one of the
+// post_patterns in the config file, such as *.md or *.markdown.
+func isPostFile(file string) bool {
+	name := path.Base(file)
+	for _, pattern := range config.PostPatterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
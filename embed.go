@@ -0,0 +1,16 @@
+//go:build embed
+
+package main
+
+import "embed"
+
+// embeddedFS bundles everything the site reads at runtime into the binary,
+// so a single file can be deployed. Embedded files carry no ModTime, so every
+// post needs a frontmatter date; loading one without fails.
+//
+//go:embed templates posts nav all:notes all:static
+var embeddedFS embed.FS
+
+func init() {
+	siteFS = embeddedFS
+}
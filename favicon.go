@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"time"
+)
+
+// iconCacheControl lets browsers keep the favicon and manifest for a day.
+const iconCacheControl = "public, max-age=86400"
+
+// FaviconHandler serves the favicon file named by favicon in the config file,
+// read from siteFS. Its content type follows the file extension.
+func FaviconHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := fs.ReadFile(siteFS, config.Favicon)
+	if errors.Is(err, fs.ErrNotExist) {
+		NotFoundHandler(w, r)
+		return
+	}
+	if err != nil {
+		logRequestError(r, err)
+		http.Error(w, "Error loading favicon", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", iconCacheControl)
+	http.ServeContent(w, r, path.Base(config.Favicon), time.Time{}, bytes.NewReader(data))
+}
+
+// hasFavicon reports whether the favicon file named in the config file exists,
+// so pages and the manifest only point at it then.
+func hasFavicon() bool {
+	_, err := fs.Stat(siteFS, config.Favicon)
+	return err == nil
+}
+
+// webManifest is a web app manifest, see https://www.w3.org/TR/appmanifest/.
+type webManifest struct {
+	Name            string         `json:"name"`
+	ShortName       string         `json:"short_name"`
+	Description     string         `json:"description,omitempty"`
+	StartURL        string         `json:"start_url"`
+	Display         string         `json:"display"`
+	BackgroundColor string         `json:"background_color"`
+	ThemeColor      string         `json:"theme_color"`
+	Icons           []manifestIcon `json:"icons,omitempty"`
+}
+
+type manifestIcon struct {
+	Src string `json:"src"`
+}
+
+// ManifestHandler serves /site.webmanifest with the site name and theme color
+// from the config file.
+func ManifestHandler(w http.ResponseWriter, r *http.Request) {
+	manifest := webManifest{
+		Name:            config.SiteTitle,
+		ShortName:       config.SiteTitle,
+		Description:     config.Description,
+		StartURL:        "/",
+		Display:         "browser",
+		BackgroundColor: config.ThemeColor,
+		ThemeColor:      config.ThemeColor,
+	}
+	if hasFavicon() {
+		manifest.Icons = []manifestIcon{{Src: "/favicon.ico"}}
+	}
+
+	w.Header().Set("Cache-Control", iconCacheControl)
+	w.Header().Set("Content-Type", "application/manifest+json")
+	json.NewEncoder(w).Encode(manifest)
+}
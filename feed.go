@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"html"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultFeedItems is the maximum number of posts included in a feed when
+// BLOG_FEED_ITEMS is unset.
+const defaultFeedItems = 20
+
+// feedItems is the maximum number of posts included in a feed. It is read
+// once from BLOG_FEED_ITEMS at startup.
+var feedItems = resolveFeedItems(os.Getenv("BLOG_FEED_ITEMS"))
+
+// resolveFeedItems parses value as a positive item limit, falling back to
+// defaultFeedItems when it is empty or invalid.
+func resolveFeedItems(value string) int {
+	if value == "" {
+		return defaultFeedItems
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		logWarn("Invalid BLOG_FEED_ITEMS %q, using %d", value, defaultFeedItems)
+		return defaultFeedItems
+	}
+	return n
+}
+
+// feedFull selects whether the RSS feed carries the full post in
+// content:encoded or only the summary with a link to the post. It is read once
+// from BLOG_FEED_FULL at startup and defaults to full content.
+var feedFull = resolveFeedFull(os.Getenv("BLOG_FEED_FULL"))
+
+// resolveFeedFull parses value as a boolean, falling back to true when it is
+// empty or invalid.
+func resolveFeedFull(value string) bool {
+	if value == "" {
+		return true
+	}
+	full, err := strconv.ParseBool(value)
+	if err != nil {
+		logWarn("Invalid BLOG_FEED_FULL %q, using full content", value)
+		return true
+	}
+	return full
+}
+
+// feedCacheTTL bounds how long a rendered feed is served from memory, so
+// edits are picked up even when the content watcher is not running.
+const feedCacheTTL = 5 * time.Minute
+
+// cachedFeed is a rendered feed document.
+type cachedFeed struct {
+	body    []byte
+	version uint64 // contentVersion when the feed was rendered
+	created time.Time
+	due     time.Time // Date of the next scheduled post then, zero if none
+}
+
+var (
+	feedCacheMu sync.Mutex
+	feedCache   = make(map[string]cachedFeed)
+)
+
+// serveFeed writes the feed of the given format, rendering it with build only
+// when the cached copy is missing, older than feedCacheTTL, was rendered
+// before the content last changed, or predates a scheduled post that has
+// since become due.
+func serveFeed(w http.ResponseWriter, format, contentType string, build func() ([]byte, error)) {
+	version := contentVersion.Load()
+	feedCacheMu.Lock()
+	entry, ok := feedCache[format]
+	feedCacheMu.Unlock()
+
+	due := ok && !entry.due.IsZero() && !now().Before(entry.due)
+	if !ok || due || entry.version != version || time.Since(entry.created) > feedCacheTTL {
+		// Look for the next scheduled post first, so one that becomes due
+		// while the feed is built still invalidates it
+		next, err := nextScheduled(postsSection)
+		var body []byte
+		if err == nil {
+			body, err = build()
+		}
+		if err != nil {
+			logError("Error generating %s feed: %v", format, err)
+			http.Error(w, "Error generating feed", http.StatusInternalServerError)
+			return
+		}
+		entry = cachedFeed{body: body, version: version, created: time.Now(), due: next}
+		feedCacheMu.Lock()
+		feedCache[format] = entry
+		feedCacheMu.Unlock()
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(entry.body)
+}
+
+// feedPosts returns the most recent published posts, newest first.
+func feedPosts() ([]PostData, error) {
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		return nil, err
+	}
+	posts = newestFirst(posts)
+	if len(posts) > feedItems {
+		posts = posts[:feedItems]
+	}
+	return posts, nil
+}
+
+// rssFeed is the root element of an RSS 2.0 document.
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	ContentNS string     `xml:"xmlns:content,attr,omitempty"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+// rssContentNS is the namespace of the content:encoded element.
+const rssContentNS = "http://purl.org/rss/1.0/modules/content/"
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	GUID        string    `xml:"guid"`
+	PubDate     string    `xml:"pubDate"`
+	Description rssCDATA  `xml:"description"`
+	Content     *rssCDATA `xml:"content:encoded,omitempty"`
+}
+
+// rssCDATA wraps a value so it is written as a CDATA section.
+type rssCDATA struct {
+	Value string `xml:",cdata"`
+}
+
+// FeedHandler serves an RSS 2.0 feed of the most recent posts.
+func FeedHandler(w http.ResponseWriter, r *http.Request) {
+	serveFeed(w, "rss", "application/rss+xml", buildRSS)
+}
+
+// buildRSS renders the RSS feed document.
+func buildRSS() ([]byte, error) {
+	posts, err := feedPosts()
+	if err != nil {
+		return nil, err
+	}
+
+	base := BaseURL()
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       config.SiteTitle,
+			Link:        base + "/",
+			Description: config.Description,
+		},
+	}
+	if feedFull {
+		feed.ContentNS = rssContentNS
+	}
+	for _, post := range posts {
+		link := base + post.Path()
+		item := rssItem{
+			Title:   post.Title,
+			Link:    link,
+			GUID:    link,
+			PubDate: post.Date.Format(time.RFC1123Z),
+		}
+		if feedFull {
+			item.Description = rssCDATA{Value: string(post.Summary)}
+			item.Content = &rssCDATA{Value: string(post.Content)}
+		} else {
+			item.Description = rssCDATA{Value: string(post.Summary) + `<p><a href="` + html.EscapeString(link) + `">Read more</a></p>`}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// atomFeed is the root element of an Atom 1.0 document.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Link      atomLink    `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// AtomHandler serves an Atom 1.0 feed of the most recent posts.
+func AtomHandler(w http.ResponseWriter, r *http.Request) {
+	serveFeed(w, "atom", "application/atom+xml; charset=utf-8", buildAtom)
+}
+
+// buildAtom renders the Atom feed document.
+func buildAtom() ([]byte, error) {
+	posts, err := feedPosts()
+	if err != nil {
+		return nil, err
+	}
+
+	base := BaseURL()
+	feed := atomFeed{
+		ID:    base + "/",
+		Title: config.SiteTitle,
+		Link:  atomLink{Rel: "self", Href: base + "/atom.xml"},
+	}
+	updated := time.Now()
+	if len(posts) > 0 {
+		updated = lastUpdated(posts)
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+
+	for _, post := range posts {
+		link := base + post.Path()
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        link,
+			Title:     post.Title,
+			Published: post.Date.Format(time.RFC3339),
+			Updated:   post.Updated.Format(time.RFC3339),
+			Link:      atomLink{Rel: "alternate", Href: link},
+			Content:   atomContent{Type: "html", Value: string(post.Content)},
+		})
+	}
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// jsonFeed is a JSON Feed 1.1 document, see https://jsonfeed.org/version/1.1.
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html"`
+	Summary       string   `json:"summary,omitempty"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// JSONFeedHandler serves a JSON Feed 1.1 document of the most recent posts.
+func JSONFeedHandler(w http.ResponseWriter, r *http.Request) {
+	serveFeed(w, "json", "application/feed+json; charset=utf-8", buildJSONFeed)
+}
+
+// buildJSONFeed renders the JSON Feed document.
+func buildJSONFeed() ([]byte, error) {
+	posts, err := feedPosts()
+	if err != nil {
+		return nil, err
+	}
+
+	base := BaseURL()
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       config.SiteTitle,
+		HomePageURL: base + "/",
+		FeedURL:     base + "/feed.json",
+		Description: config.Description,
+		Items:       []jsonFeedItem{},
+	}
+	for _, post := range posts {
+		link := base + post.Path()
+		item := jsonFeedItem{
+			ID:            link,
+			URL:           link,
+			Title:         post.Title,
+			ContentHTML:   string(post.Content),
+			Summary:       post.Description,
+			DatePublished: post.Date.Format(time.RFC3339),
+			Tags:          post.Tags,
+		}
+		if !post.Updated.Equal(post.Date) {
+			item.DateModified = post.Updated.Format(time.RFC3339)
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	// content_html is meant to be read as HTML, so keep it unescaped
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
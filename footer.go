@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// Footer holds what the copyright line in the page footer is built from.
+type Footer struct {
+	Name      string
+	StartYear int
+	Year      int // Current year, computed when the page is rendered
+}
+
+// Years returns the copyright years, e.g. "2019–2025", or a single year when
+// the site started this year.
+func (f Footer) Years() string {
+	if f.StartYear == 0 || f.StartYear >= f.Year {
+		return strconv.Itoa(f.Year)
+	}
+	return strconv.Itoa(f.StartYear) + "–" + strconv.Itoa(f.Year)
+}
+
+// siteFooter returns the footer of the active config for the current year.
+func siteFooter() Footer {
+	name := config.Copyright
+	if name == "" {
+		name = config.SiteTitle
+	}
+	return Footer{Name: name, StartYear: config.StartYear, Year: time.Now().In(displayLocation).Year()}
+}
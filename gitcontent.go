@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultRepoDir is where content_repo is cloned when no content directory
+// is set.
+const defaultRepoDir = "content"
+
+// gitTimeout bounds a single clone or fetch of the content repository.
+const gitTimeout = 2 * time.Minute
+
+// contentPullInterval is how often the content repository is fetched again,
+// read once from BLOG_CONTENT_PULL_INTERVAL at startup.
+var contentPullInterval = resolveDuration("BLOG_CONTENT_PULL_INTERVAL", 5*time.Minute)
+
+// ContentRepo keeps a local checkout of the Git repository holding posts/,
+// notes/, and nav/ up to date with its remote branch.
+type ContentRepo struct {
+	URL    string
+	Branch string // Empty means the remote's default branch
+	Dir    string
+	Token  string // Sent as HTTP basic auth for https URLs, if set
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// git runs git with args, authenticating with the token through the
+// environment so it never shows up in the process list or .git/config.
+func (c *ContentRepo) git(args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if c.Token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + c.Token))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+		)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Sync clones the repository into Dir, or fetches the branch and resets the
+// checkout to it when Dir already holds a clone. On error the files in Dir
+// are left as they were.
+func (c *ContentRepo) Sync() error {
+	if _, err := os.Stat(filepath.Join(c.Dir, ".git")); err != nil {
+		args := []string{"clone", "--depth", "1"}
+		if c.Branch != "" {
+			args = append(args, "--branch", c.Branch)
+		}
+		return c.git(append(args, "--", c.URL, c.Dir)...)
+	}
+
+	ref := c.Branch
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := c.git("-C", c.Dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+		return err
+	}
+	return c.git("-C", c.Dir, "reset", "--hard", "FETCH_HEAD")
+}
+
+// Start syncs the repository every interval in the background, reloading
+// the content after each successful sync. Failures are logged and the last
+// synced content keeps being served.
+func (c *ContentRepo) Start(interval time.Duration) {
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.Sync(); err != nil {
+					logError("Error syncing content repository, serving last synced content: %v", err)
+					continue
+				}
+				if _, err := reloadContent(); err != nil {
+					logError("Error reloading content: %v", err)
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the periodic sync, waiting for one in progress to finish.
+func (c *ContentRepo) Close() {
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+	}
+}
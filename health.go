@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+)
+
+// HealthHandler reports whether the server can serve pages: the posts
+// directory must be readable and the templates must parse. It only lists the
+// directory, so it is cheap enough for frequent readiness probes.
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if _, err := fs.ReadDir(contentFS(), postsSection.Dir); err != nil {
+		logError("Health check failed: %v", err)
+		http.Error(w, "posts directory unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if err := LoadTemplates(); err != nil {
+		logError("Health check failed: %v", err)
+		http.Error(w, "templates unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// highlightCSSPath is where the stylesheet for the highlighted code is served.
+const highlightCSSPath = "/static/highlight.css"
+
+// highlightOptions make the highlighter emit classes instead of inline styles,
+// so the colors come from the stylesheet at highlightCSSPath.
+var highlightOptions = []chromahtml.Option{chromahtml.WithClasses(true)}
+
+// highlightCSS renders the stylesheet for codeStyle.
+func highlightCSS() ([]byte, error) {
+	var buf bytes.Buffer
+	err := chromahtml.New(highlightOptions...).WriteCSS(&buf, styles.Get(codeStyle))
+	return buf.Bytes(), err
+}
+
+// HighlightCSSHandler serves the stylesheet for the highlighted code.
+func HighlightCSSHandler(w http.ResponseWriter, r *http.Request) {
+	css, err := highlightCSS()
+	if err != nil {
+		http.Error(w, "Error generating stylesheet", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.Write(css)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// imageAttributes are added to every image that does not set them already,
+// so images below the fold are only fetched when the reader scrolls to them.
+var imageAttributes = []struct{ name, value string }{
+	{"loading", "lazy"},
+	{"decoding", "async"},
+}
+
+type imageTransformer struct{}
+
+func (t *imageTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if img, ok := n.(*ast.Image); ok && entering {
+			for _, attr := range imageAttributes {
+				if _, ok := img.AttributeString(attr.name); !ok {
+					img.SetAttributeString(attr.name, []byte(attr.value))
+				}
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+type imageExtension struct{}
+
+// imageExt makes images load lazily and decode asynchronously.
+var imageExt = &imageExtension{}
+
+func (e *imageExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(&imageTransformer{}, 500)),
+	)
+}
+
+// KindFigure is the node kind of a captioned image.
+var KindFigure = ast.NewNodeKind("Figure")
+
+// Figure is an image standing alone in its paragraph, rendered as a <figure>
+// with the image title as its <figcaption>.
+type Figure struct {
+	ast.BaseBlock
+	Caption []byte
+}
+
+func (n *Figure) Kind() ast.NodeKind { return KindFigure }
+
+func (n *Figure) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Caption": string(n.Caption)}, nil)
+}
+
+type figureTransformer struct{}
+
+func (t *figureTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var paragraphs []*ast.Paragraph
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if p, ok := n.(*ast.Paragraph); ok && entering && p.ChildCount() == 1 {
+			if img, ok := p.FirstChild().(*ast.Image); ok && len(img.Title) > 0 {
+				paragraphs = append(paragraphs, p)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// Replace after walking so the walk does not see a modified tree
+	for _, p := range paragraphs {
+		img := p.FirstChild().(*ast.Image)
+		figure := &Figure{Caption: img.Title}
+		figure.AppendChild(figure, img)
+		p.Parent().ReplaceChild(p.Parent(), p, figure)
+	}
+}
+
+type figureRenderer struct{}
+
+func (r *figureRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindFigure, r.renderFigure)
+}
+
+func (r *figureRenderer) renderFigure(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString("<figure>\n")
+		return ast.WalkContinue, nil
+	}
+	w.WriteString("\n<figcaption>")
+	w.Write(util.EscapeHTML(util.UnescapePunctuations(n.(*Figure).Caption)))
+	w.WriteString("</figcaption>\n</figure>\n")
+	return ast.WalkContinue, nil
+}
+
+type figureExtension struct{}
+
+// figureExt renders images that stand alone in a paragraph and have a title
+// as figures captioned with the title. Inline images stay plain <img> tags.
+var figureExt = &figureExtension{}
+
+func (e *figureExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(&figureTransformer{}, 500)),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(util.Prioritized(&figureRenderer{}, 500)),
+	)
+}
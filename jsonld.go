@@ -0,0 +1,39 @@
+package main
+
+import "time"
+
+// articleLD is the schema.org Article describing a post, embedded in the post
+// page as JSON-LD. html/template marshals and escapes it inside the
+// application/ld+json script, so no value can end the script early.
+type articleLD struct {
+	Context       string   `json:"@context"`
+	Type          string   `json:"@type"`
+	Headline      string   `json:"headline"`
+	Description   string   `json:"description,omitempty"`
+	Image         string   `json:"image,omitempty"`
+	DatePublished string   `json:"datePublished"`
+	DateModified  string   `json:"dateModified"`
+	Author        personLD `json:"author"`
+	URL           string   `json:"url"`
+}
+
+type personLD struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// newArticleLD returns the Article metadata of post published at url, with
+// image as its preview image when set.
+func newArticleLD(post PostData, url, image string) articleLD {
+	return articleLD{
+		Context:       "https://schema.org",
+		Type:          "Article",
+		Headline:      post.Title,
+		Description:   post.Description,
+		Image:         image,
+		DatePublished: post.Date.Format(time.RFC3339),
+		DateModified:  post.Updated.Format(time.RFC3339),
+		Author:        personLD{Type: "Person", Name: post.Author},
+		URL:           url,
+	}
+}
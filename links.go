@@ -0,0 +1,140 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// isExternalLink reports whether dest is an absolute http or https URL on a
+// different host than the site's base URL.
+func isExternalLink(dest string) bool {
+	u, err := url.Parse(dest)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	base, err := url.Parse(BaseURL())
+	if err != nil {
+		return true
+	}
+	return !strings.EqualFold(u.Host, base.Host)
+}
+
+type externalLinkTransformer struct{}
+
+func (t *externalLinkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	if !config.ExternalLinksNewTab {
+		return
+	}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var dest []byte
+		switch link := n.(type) {
+		case *ast.Link:
+			dest = link.Destination
+		case *ast.AutoLink:
+			if link.AutoLinkType != ast.AutoLinkURL {
+				return ast.WalkContinue, nil
+			}
+			dest = link.URL(reader.Source())
+		default:
+			return ast.WalkContinue, nil
+		}
+		if isExternalLink(string(dest)) {
+			n.SetAttributeString("target", []byte("_blank"))
+			n.SetAttributeString("rel", []byte("noopener noreferrer"))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// sourceFileKey holds the path of the Markdown file being converted, empty
+// when the Markdown does not come from a file.
+var sourceFileKey = parser.NewContextKey()
+
+// postLinkPath returns the URL path of the post a relative link to a Markdown
+// file points at, e.g. other-post.md#setup becomes /post/other-post#setup.
+// Targets are resolved against the directory of file, the Markdown file the
+// link appears in, or the posts directory when file is empty; paths starting
+// with a slash are resolved against the content root. ok is false for links
+// that are not relative links to a post file; a link to a missing post logs a
+// warning and is left as it is.
+func postLinkPath(file, dest string) (string, bool) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || !isPostFile(u.Path) {
+		return "", false
+	}
+
+	dir := postsSection.Dir
+	if file != "" {
+		dir = path.Dir(file)
+	}
+	target := path.Join(dir, u.Path)
+	if strings.HasPrefix(u.Path, "/") {
+		target = path.Clean(strings.TrimPrefix(u.Path, "/"))
+	}
+	name, inPosts := strings.CutPrefix(target, postsSection.Dir+"/")
+	if !inPosts || !fileExists(target) {
+		logWarn("Link to missing post %s", dest)
+		return "", false
+	}
+
+	// The target's frontmatter may override the slug; its body is not rendered,
+	// so posts linking to each other cannot recurse
+	post := PostData{Section: postsSection.Name, Slug: strings.TrimSuffix(name, path.Ext(name))}
+	if matter, _, err := ReadMarkdown(target); err == nil && matter.Slug != "" {
+		post.Slug = matter.Slug
+	}
+	link := slashPath("/" + post.Section + "/" + post.Slug)
+	if u.Fragment != "" {
+		link += "#" + u.Fragment
+	}
+	return link, true
+}
+
+type postLinkTransformer struct{}
+
+func (t *postLinkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	file, _ := pc.Get(sourceFileKey).(string)
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if link, ok := n.(*ast.Link); ok && entering {
+			if target, ok := postLinkPath(file, string(link.Destination)); ok {
+				link.Destination = []byte(target)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+type postLinkExtension struct{}
+
+// postLinkExt rewrites relative links to Markdown files in the posts
+// directory, such as [see this](other-post.md), to the URL of that post.
+var postLinkExt = &postLinkExtension{}
+
+func (e *postLinkExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(&postLinkTransformer{}, 500)),
+	)
+}
+
+type externalLinkExtension struct{}
+
+// externalLinkExt opens links to other sites in a new tab when
+// external_links_new_tab is set in the config file. Links within the site are
+// left untouched.
+var externalLinkExt = &externalLinkExtension{}
+
+func (e *externalLinkExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(&externalLinkTransformer{}, 500)),
+	)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Logs are written through logInfo, logWarn, logError, and logFatal. With
+// BLOG_LOG_FORMAT=json each line is a JSON object for log aggregators;
+// otherwise lines are written with the standard log package.
+
+// jsonLogger writes JSON log lines, or is nil for the human-readable format.
+var jsonLogger = newJSONLogger(os.Getenv("BLOG_LOG_FORMAT"))
+
+// newJSONLogger returns a JSON logger when format is "json" and nil otherwise.
+func newJSONLogger(format string) *slog.Logger {
+	if format != "json" {
+		return nil
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return a
+			}
+			switch a.Key {
+			case slog.TimeKey:
+				a.Key = "timestamp"
+			case slog.MessageKey:
+				a.Key = "message"
+			}
+			return a
+		},
+	}))
+}
+
+// logAt writes a log line at level. attrs are only included in JSON logs; the
+// human-readable line is the formatted message alone.
+func logAt(level slog.Level, attrs []slog.Attr, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if jsonLogger == nil {
+		if level == slog.LevelWarn {
+			msg = "Warning: " + msg
+		}
+		log.Print(msg)
+		return
+	}
+	jsonLogger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+func logInfo(format string, args ...any)  { logAt(slog.LevelInfo, nil, format, args...) }
+func logWarn(format string, args ...any)  { logAt(slog.LevelWarn, nil, format, args...) }
+func logError(format string, args ...any) { logAt(slog.LevelError, nil, format, args...) }
+
+// logFatal logs an error and exits, like log.Fatalf.
+func logFatal(format string, args ...any) {
+	logAt(slog.LevelError, nil, format, args...)
+	os.Exit(1)
+}
+
+// requestAttrs describes r for JSON logs.
+func requestAttrs(r *http.Request) []slog.Attr {
+	return []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("remote_addr", r.RemoteAddr),
+	}
+}
+
+// logRequestError logs an error that occurred while serving r.
+func logRequestError(r *http.Request, err error) {
+	logAt(slog.LevelError, requestAttrs(r), "Error serving %s %s: %v", r.Method, r.URL.Path, err)
+}
+
+// logAccess writes the access log line of a served request.
+func logAccess(r *http.Request, status, size int, duration time.Duration) {
+	attrs := append(requestAttrs(r),
+		slog.Int("status", status),
+		slog.Int("bytes", size),
+		slog.Float64("duration_ms", float64(duration)/float64(time.Millisecond)),
+	)
+	logAt(slog.LevelInfo, attrs, "%s %s %d %dB %s", r.Method, r.URL.Path, status, size, duration)
+}
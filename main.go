@@ -2,64 +2,368 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
-	"log"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/adrg/frontmatter"
+	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/yuin/goldmark"
+	emoji "github.com/yuin/goldmark-emoji"
 	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
 // Post represents a blog post with a title, date, and content.
 type PostData struct {
-	Title   string
-	Date    time.Time
-	Slug    string
-	Content template.HTML // Content after converting from Markdown
+	Title       string
+	Date        time.Time
+	Updated     time.Time // Last update, the same as Date unless set
+	Slug        string
+	Author      string
+	Tags        []string
+	Draft       bool
+	Weight      *int          // Optional ordering weight, nil when unset
+	Section     string        // Name of the section the post belongs to
+	Layout      string        // Template to render the post with instead of "post"
+	Series      string        // Name of the series the post belongs to, if any
+	Part        *int          // Optional position within the series, nil when unset
+	ReadingTime int           // Estimated reading time in minutes
+	WordCount   int           // Words in the body, excluding code blocks
+	CharCount   int           // Non-whitespace characters, excluding code blocks
+	Content     template.HTML // Content after converting from Markdown
+	Summary     template.HTML // Short excerpt for listings
+	Description string        // Plain-text description for link previews
+	Image       string        // Image for link previews as set in the frontmatter
+	NoComments  bool          // Comments turned off with comments: false
+
+	TableOfContents []Heading
+	HasMermaid      bool // Whether the content contains Mermaid diagrams
+	HasMath         bool // Whether the content contains math for KaTeX
+
+	source       []byte // Markdown body without frontmatter
+	slugFromName bool   // Slug was derived from the file name, not frontmatter
+}
+
+// FrontMatter holds the metadata parsed from the top of a Markdown file.
+type FrontMatter struct {
+	Title       string   `yaml:"title"`
+	Date        string   `yaml:"date"`
+	Updated     string   `yaml:"updated"`
+	Slug        string   `yaml:"slug"`
+	Author      string   `yaml:"author"`
+	Draft       bool     `yaml:"draft"`
+	Tags        []string `yaml:"tags"`
+	Summary     string   `yaml:"summary"`
+	Description string   `yaml:"description"`
+	Weight      *int     `yaml:"weight"`
+	Layout      string   `yaml:"layout"`
+	Series      string   `yaml:"series"`
+	Part        *int     `yaml:"part"`
+	Image       string   `yaml:"image"`
+	Comments    *bool    `yaml:"comments"`
 }
 
+// dateLayouts lists the accepted formats for the frontmatter date.
+var dateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+// postsPerPage is the number of posts shown on each page of a post listing.
+const postsPerPage = 10
+
 // TemplateData holds the data passed to the template.
 type TemplateData struct {
-	Title string
-	Posts []PostData
+	Title       string
+	Canonical   string // Absolute URL for the canonical link tag
+	Theme       string // Color theme chosen by the reader, see themeFor
+	Posts       []PostData
+	CurrentPage int
+	TotalPages  int
+	HasPrev     bool
+	HasNext     bool
+	PrevPage    int
+	NextPage    int
+	PrevLink    string // Link to the previous page, if any
+	NextLink    string // Link to the next page, if any
+	PrevURL     string // Absolute URL of the previous page for rel="prev", if any
+	NextURL     string // Absolute URL of the next page for rel="next", if any
+}
+
+// ReadMarkdown reads a Markdown file and splits it into its frontmatter and body.
+func ReadMarkdown(filePath string) (FrontMatter, []byte, error) {
+	var matter FrontMatter
+	md, err := fs.ReadFile(contentFS(), filePath)
+	if err != nil {
+		return matter, nil, err
+	}
+
+	body, err := frontmatter.Parse(bytes.NewReader(md), &matter)
+	if err != nil {
+		return matter, nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return matter, body, nil
+}
+
+// defaultCodeStyle is the chroma style used when BLOG_CODE_STYLE is unset.
+const defaultCodeStyle = "dracula"
+
+// codeStyle is the chroma style used for syntax highlighting. It is read once
+// from BLOG_CODE_STYLE at startup.
+var codeStyle = resolveCodeStyle(os.Getenv("BLOG_CODE_STYLE"))
+
+// resolveCodeStyle returns name if it is a registered chroma style, falling
+// back to defaultCodeStyle otherwise.
+func resolveCodeStyle(name string) string {
+	if name == "" {
+		return defaultCodeStyle
+	}
+	if _, ok := styles.Registry[name]; !ok {
+		logWarn("Unknown code style %q, using %q", name, defaultCodeStyle)
+		return defaultCodeStyle
+	}
+	return name
 }
 
 // RenderMarkdown converts Markdown content to HTML.
 func RenderMarkdown(filePath string) (template.HTML, error) {
-	md, err := os.ReadFile(filePath)
+	_, body, err := ReadMarkdown(filePath)
 	if err != nil {
 		return "", err
 	}
+	body, _ = splitMore(body)
+	content, _, err := convertMarkdownFile(filePath, body)
+	return content, err
+}
 
-	markdown := goldmark.New(
+// newMarkdown builds the goldmark converter used for all posts and pages.
+// Raw HTML is only rendered when it is sanitized afterwards.
+func newMarkdown() goldmark.Markdown {
+	var rendererOptions []renderer.Option
+	if sanitizeHTML {
+		rendererOptions = append(rendererOptions, html.WithUnsafe())
+	}
+	return goldmark.New(
 		goldmark.WithExtensions(
+			extension.GFM,
+			extension.Footnote,
+			extension.DefinitionList,
+			mathExt,
+			anchorExt,
+			imageExt,
+			figureExt,
+			externalLinkExt,
+			postLinkExt,
+			mermaidExt,
+			emoji.New(emoji.WithRenderingMethod(emoji.Unicode)),
 			highlighting.NewHighlighting(
-				highlighting.WithStyle("dracula"),
+				highlighting.WithStyle(codeStyle),
+				highlighting.WithFormatOptions(highlightOptions...),
 			),
 		),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+		),
+		goldmark.WithRendererOptions(rendererOptions...),
 	)
-	remainingMd, err := frontmatter.Parse(strings.NewReader(string(md)), &md)
+}
+
+// markdown is built once and shared; goldmark converters are safe for
+// concurrent use.
+var markdown = newMarkdown()
+
+// convertMarkdown converts a Markdown body without frontmatter to HTML,
+// sanitized when BLOG_SANITIZE is on, and returns the table of contents built
+// from its headings.
+func convertMarkdown(body []byte) (template.HTML, []Heading, error) {
+	return convertMarkdownFile("", body)
+}
+
+// convertMarkdownFile is convertMarkdown for the body of file, against which
+// relative links are resolved.
+func convertMarkdownFile(file string, body []byte) (template.HTML, []Heading, error) {
+	pc := parser.NewContext()
+	pc.Set(sourceFileKey, file)
+	doc := markdown.Parser().Parse(text.NewReader(body), parser.WithContext(pc))
+	var buf bytes.Buffer
+	err := markdown.Renderer().Render(&buf, body, doc)
 	if err != nil {
-		panic(err)
+		return "", nil, err
 	}
+	return template.HTML(sanitize(buf.Bytes())), tableOfContents(doc, body), nil
+}
 
-	var buf bytes.Buffer
-	err = markdown.Convert([]byte(remainingMd), &buf)
+// parseDate parses a frontmatter date in any of the accepted layouts. Dates
+// without an offset are taken to be in displayLocation, so a plain day is
+// never shown as the day before.
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.ParseInLocation(layout, value, displayLocation); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q, expected RFC3339 or 2006-01-02", value)
+}
+
+// loadPostFile reads a post from disk. The title and date come from the
+// frontmatter, falling back to the filename and the file's ModTime.
+func loadPostFile(file string) (PostData, error) {
+	matter, body, err := ReadMarkdown(file)
+	if err != nil {
+		return PostData{}, err
+	}
+
+	// Load and convert the Markdown content to HTML
+	body, intro := splitMore(body)
+	content, toc, err := convertMarkdownFile(file, body)
 	if err != nil {
-		panic(err)
+		return PostData{}, err
+	}
+	var introHTML template.HTML
+	if intro != nil {
+		introHTML, _, err = convertMarkdownFile(file, intro)
+		if err != nil {
+			return PostData{}, err
+		}
+	}
+
+	filename := filepath.Base(file)
+	slug := matter.Slug
+	if slug == "" {
+		slug = strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+	title := matter.Title
+	if title == "" {
+		title = CleanTitle(filename)
+	}
+
+	var date time.Time
+	if matter.Date != "" {
+		date, err = parseDate(matter.Date)
+		if err != nil {
+			return PostData{}, fmt.Errorf("%s: %w", file, err)
+		}
+	} else {
+		fileInfo, err := fs.Stat(contentFS(), file)
+		if err != nil {
+			return PostData{}, err
+		}
+		// Embedded files have no ModTime, so files there need a date
+		if fileInfo.ModTime().IsZero() {
+			return PostData{}, fmt.Errorf("%s: no date in the frontmatter and no modification time to fall back to", file)
+		}
+		date = fileInfo.ModTime()
+	}
+	date = date.In(displayLocation)
+
+	updated := date
+	if matter.Updated != "" {
+		updated, err = parseDate(matter.Updated)
+		if err != nil {
+			return PostData{}, fmt.Errorf("%s: updated: %w", file, err)
+		}
+		updated = updated.In(displayLocation)
+	}
+
+	author := strings.TrimSpace(matter.Author)
+	if author == "" {
+		author = siteAuthor()
+	}
+
+	summary := summarize(matter, content, introHTML)
+	description := matter.Description
+	if description == "" {
+		description = plainText(summary)
 	}
-	return template.HTML(buf.String()), nil
+
+	return PostData{
+		Title:       title,
+		Date:        date,
+		Updated:     updated,
+		Slug:        slug,
+		Author:      author,
+		Tags:        matter.Tags,
+		Draft:       matter.Draft,
+		Weight:      matter.Weight,
+		Layout:      matter.Layout,
+		Series:      strings.TrimSpace(matter.Series),
+		Part:        matter.Part,
+		ReadingTime: readingTime(body),
+		WordCount:   wordCount(body),
+		CharCount:   charCount(body),
+		Content:     content,
+		Summary:     summary,
+		Description: description,
+		Image:       strings.TrimSpace(matter.Image),
+		NoComments:  matter.Comments != nil && !*matter.Comments,
+
+		TableOfContents: toc,
+		HasMermaid:      strings.Contains(string(content), mermaidClass),
+		HasMath:         strings.Contains(string(content), mathClass),
+
+		source:       body,
+		slugFromName: matter.Slug == "",
+	}, nil
+}
+
+// BaseURL returns the public URL of the site without a trailing slash. It is
+// read from BLOG_BASE_URL, falling back to base_url in the config file.
+func BaseURL() string {
+	if u := os.Getenv("BLOG_BASE_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return strings.TrimSuffix(config.BaseURL, "/")
+}
+
+// absoluteURL resolves a link from content or config against the base URL.
+// Absolute URLs are returned unchanged.
+func absoluteURL(link string) string {
+	if u, err := url.Parse(link); err == nil && u.IsAbs() {
+		return link
+	}
+	return BaseURL() + "/" + strings.TrimPrefix(link, "/")
+}
+
+// previewImageURL returns the absolute URL of the image shown when post is
+// shared: its image frontmatter, falling back to image in the config file.
+// It is empty when neither is set.
+func previewImageURL(post PostData) string {
+	image := post.Image
+	if image == "" {
+		image = config.Image
+	}
+	if image == "" {
+		return ""
+	}
+	return absoluteURL(image)
+}
+
+// canonicalURL returns the absolute URL of the page requested by r, without
+// its query, for the canonical link tag.
+func canonicalURL(r *http.Request) string {
+	return BaseURL() + slashPath(r.URL.EscapedPath())
 }
+
 func CleanTitle(filename string) string {
 	// Remove the extension (.md) if present
 	title := strings.TrimSuffix(filename, filepath.Ext(filename))
@@ -73,68 +377,300 @@ func CleanTitle(filename string) string {
 	return title
 }
 
-// LoadBlogPosts loads the blog posts from Markdown files and sorts them by date.
-func LoadBlogPosts() ([]PostData, error) {
-	var posts []PostData
-
-	// Get all markdown files from the "content" directory
-	files, err := filepath.Glob("posts/*.md")
-	if err != nil {
-		return nil, err
-	}
-
-	for _, file := range files {
-		// Extract the filename without the extension to use as the Title and Slug
-		filename := filepath.Base(file)
-		slug := strings.TrimSuffix(filename, filepath.Ext(filename))
-		title := CleanTitle(filename)
+// showDrafts reports whether draft posts may be served by slug. It is enabled
+// by setting BLOG_SHOW_DRAFTS=1 and is meant for local previews only.
+func showDrafts() bool {
+	return os.Getenv("BLOG_SHOW_DRAFTS") == "1"
+}
 
-		// Use the file's ModTime as the post's Date
-		fileInfo, err := os.Stat(file)
-		if err != nil {
-			return nil, err
+// sortPosts orders posts for listings. Posts with a weight come first, in
+// ascending weight order; posts without a weight follow. Within equal weights,
+// and among unweighted posts, the newest post comes first.
+func sortPosts(posts []PostData) {
+	sort.SliceStable(posts, func(i, j int) bool {
+		wi, wj := posts[i].Weight, posts[j].Weight
+		switch {
+		case wi != nil && wj == nil:
+			return true
+		case wi == nil && wj != nil:
+			return false
+		case wi != nil && wj != nil && *wi != *wj:
+			return *wi < *wj
 		}
+		return posts[i].Date.After(posts[j].Date)
+	})
+}
 
-		// Load and convert the Markdown content to HTML
-		content, err := RenderMarkdown(file)
-		if err != nil {
-			return nil, err
-		}
+// newestFirst returns a copy of posts sorted by date alone, for feeds and
+// other chronological views that ignore weights.
+func newestFirst(posts []PostData) []PostData {
+	sorted := append([]PostData(nil), posts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.After(sorted[j].Date)
+	})
+	return sorted
+}
 
-		// Create a Post object
-		post := PostData{
-			Title:   title,
-			Date:    fileInfo.ModTime(),
-			Slug:    slug,
-			Content: content,
+// lastUpdated returns the most recent update time across posts, or the zero
+// time when there are none.
+func lastUpdated(posts []PostData) time.Time {
+	var latest time.Time
+	for _, post := range posts {
+		if post.Updated.After(latest) {
+			latest = post.Updated
 		}
-		posts = append(posts, post)
 	}
+	return latest
+}
 
-	// Sort posts by date (latest first)
-	sort.Slice(posts, func(i, j int) bool {
-		return posts[i].Date.After(posts[j].Date)
-	})
-
-	return posts, nil
+// LoadBlogPosts loads the published blog posts from the posts section and
+// sorts them with sortPosts. Drafts are skipped.
+func LoadBlogPosts() ([]PostData, error) {
+	return LoadSectionPosts(postsSection)
 }
 
+// defaultAddr is the address the server listens on when neither BLOG_ADDR
+// nor -addr is set.
+const defaultAddr = ":8090"
+
+// shutdownTimeout bounds how long in-flight requests may take to finish once
+// a shutdown signal is received.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
+	addr := os.Getenv("BLOG_ADDR")
+	if addr == "" {
+		addr = defaultAddr
+	}
+	flag.StringVar(&addr, "addr", addr, "address to listen on (overrides BLOG_ADDR)")
+	httpAddr := os.Getenv("BLOG_HTTP_ADDR")
+	if httpAddr == "" {
+		httpAddr = defaultHTTPAddr
+	}
+	flag.StringVar(&httpAddr, "http-addr", httpAddr, "address of the HTTP-to-HTTPS redirect listener when TLS is enabled (overrides BLOG_HTTP_ADDR)")
+	build := flag.String("build", "", "write the static site to this directory instead of serving")
+	generate := flag.Bool("generate", false, "shorthand for -build public")
+	configFile := flag.String("config", defaultConfigFile, "path to the site config file")
+	strict := flag.Bool("strict", false, "refuse to start when two posts share a slug")
+	redirectsFile := flag.String("redirects", defaultRedirectsFile, "path to the YAML map of old paths to new ones")
+	flag.Parse()
+	if *generate && *build == "" {
+		*build = "public"
+	}
+
+	cfg, err := LoadConfig(*configFile)
+	if err != nil {
+		logFatal("Error loading config %s: %v", *configFile, err)
+	}
+	config = cfg
+	if os.Getenv("BLOG_CSP") == "" {
+		contentSecurityPolicy = allowComments(contentSecurityPolicy, config.Comments.Provider)
+	}
+	contentDir = resolveContentDir()
+	var contentRepo *ContentRepo
+	if config.ContentRepo != "" {
+		if contentDir == "" {
+			contentDir = defaultRepoDir
+		}
+		contentRepo = &ContentRepo{
+			URL:    config.ContentRepo,
+			Branch: config.ContentBranch,
+			Dir:    contentDir,
+			Token:  os.Getenv("BLOG_CONTENT_REPO_TOKEN"),
+		}
+		if err := contentRepo.Sync(); err != nil {
+			logError("Error syncing content repository, serving last synced content: %v", err)
+		}
+	}
+	navPages = detectNavPages()
+	redirects, err = LoadRedirects(*redirectsFile)
+	if err != nil {
+		logFatal("Error loading redirects %s: %v", *redirectsFile, err)
+	}
+	slugsOK, err := checkSlugs()
+	if err != nil {
+		logError("Error checking slugs: %v", err)
+	}
+	if !slugsOK && *strict {
+		logFatal("Refusing to start with invalid content in strict mode")
+	}
+
 	// Check if we should generate static files instead of running a server
-	if len(os.Args) > 1 && os.Args[1] == "--generate" {
-		if err := GenerateStaticSite("public"); err != nil {
-			log.Fatal(err)
+	if *build != "" {
+		if err := GenerateStaticSite(*build); err != nil {
+			logFatal("%v", err)
 		}
 		fmt.Println("Static site generated successfully!")
 		return
 	}
 
+	if err := LoadTemplates(); err != nil {
+		logFatal("%v", err)
+	}
+
+	viewsFile := os.Getenv("BLOG_VIEWS_FILE")
+	if viewsFile == "" {
+		viewsFile = defaultViewsFile
+	}
+	views, err = LoadViewCounter(viewsFile)
+	if err != nil {
+		logFatal("Error loading view counts %s: %v", viewsFile, err)
+	}
+	views.Start(viewsFlushInterval)
+
+	mentionsFile := os.Getenv("BLOG_WEBMENTIONS_FILE")
+	if mentionsFile == "" {
+		mentionsFile = defaultMentionsFile
+	}
+	mentions, err = LoadMentionStore(mentionsFile)
+	if err != nil {
+		logFatal("Error loading webmentions %s: %v", mentionsFile, err)
+	}
+	if contentRepo != nil {
+		contentRepo.Start(contentPullInterval)
+		defer contentRepo.Close()
+	}
+
+	// Without the watcher, edits are still picked up by the ModTime check
+	stopWatching, err := WatchContent(postCache, contentDirs())
+	if err != nil {
+		logWarn("Not watching content for changes: %v", err)
+	} else {
+		defer stopWatching()
+	}
+
 	http.HandleFunc("/", HomeHandler)
-	http.HandleFunc("/about", AboutHandler)
-	http.HandleFunc("/contact", ContactHandler)
+	// Missing nav pages fall through to the 404 page of "/"
+	if navPages.HasAbout {
+		http.HandleFunc("/about", AboutHandler)
+	}
+	if navPages.HasContact {
+		http.HandleFunc("/contact", ContactHandler)
+	}
 	http.HandleFunc("/post/", PostHandler)
-	fmt.Println("Server is running...")
-	log.Fatal(http.ListenAndServe(":8090", nil))
+	http.HandleFunc("/note/", NoteHandler)
+	http.HandleFunc("/preview/", PreviewHandler)
+	http.HandleFunc("/notes", NotesHandler)
+	http.HandleFunc("/feed.xml", FeedHandler)
+	http.HandleFunc("/atom.xml", AtomHandler)
+	http.HandleFunc("/feed.json", JSONFeedHandler)
+	http.HandleFunc("/sitemap.xml", SitemapHandler)
+	http.HandleFunc("/robots.txt", RobotsHandler)
+	http.HandleFunc("/favicon.ico", FaviconHandler)
+	http.HandleFunc("/site.webmanifest", ManifestHandler)
+	http.HandleFunc("/tags", TagsHandler)
+	http.HandleFunc("/tags/", TagHandler)
+	http.HandleFunc("/authors/", AuthorHandler)
+	http.HandleFunc("/series/", SeriesHandler)
+	http.Handle("/static/", StaticHandler())
+	http.HandleFunc(highlightCSSPath, HighlightCSSHandler)
+	http.HandleFunc("/search", SearchHandler)
+	http.HandleFunc("/archive", ArchiveHandler)
+	http.HandleFunc("/random", RandomHandler)
+	http.HandleFunc("/theme", ThemeHandler)
+	http.HandleFunc("/webmention", WebmentionHandler)
+	http.HandleFunc("/api/posts", APIPostsHandler)
+	http.HandleFunc("/api/post/", APIPostHandler)
+	http.HandleFunc("/api/tags", APITagsHandler)
+	http.HandleFunc("/api/stats", APIStatsHandler)
+	http.HandleFunc("/healthz", HealthHandler)
+	http.HandleFunc("/version", VersionHandler)
+	http.HandleFunc("/admin/reload", AdminReloadHandler)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           LogRequests(SecurityHeaders(VersionHeader(Redirects(TrailingSlash(Gzip(Timeout(http.DefaultServeMux))))))),
+		ReadHeaderTimeout: readTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	tlsConf, err := loadTLS()
+	if err != nil {
+		logFatal("%v", err)
+	}
+
+	// With TLS, plain HTTP requests are redirected to the HTTPS server
+	var redirect *http.Server
+	if tlsConf != nil {
+		redirect = &http.Server{
+			Addr:              httpAddr,
+			Handler:           LogRequests(tlsConf.redirectHandler(addr)),
+			ReadHeaderTimeout: readTimeout,
+			ReadTimeout:       readTimeout,
+			WriteTimeout:      writeTimeout,
+			IdleTimeout:       idleTimeout,
+		}
+		go func() {
+			logInfo("Redirecting HTTP on %s to HTTPS", httpAddr)
+			if err := redirect.ListenAndServe(); err != http.ErrServerClosed {
+				logError("Error in HTTP redirect listener: %v", err)
+			}
+		}()
+	}
+
+	// Stop accepting connections on SIGINT/SIGTERM and let in-flight
+	// requests finish before exiting
+	idle := make(chan struct{})
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+		<-stop
+
+		logInfo("Shutting down server...")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if redirect != nil {
+			redirect.Shutdown(ctx)
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			logError("Error during shutdown: %v", err)
+		}
+		close(idle)
+	}()
+
+	logInfo("Server is running on %s (%s)", addr, tlsConf)
+	if err := tlsConf.listenAndServe(server); err != http.ErrServerClosed {
+		logFatal("%v", err)
+	}
+	<-idle
+	if err := views.Close(); err != nil {
+		logError("Error saving view counts: %v", err)
+	}
+	logInfo("Server stopped")
+}
+
+// staticPage is a file written by GenerateStaticSite from a handler's output.
+type staticPage struct {
+	filename string
+	path     string
+	handler  http.HandlerFunc
+}
+
+// staticListing is a paginated listing written by GenerateStaticSite, with
+// every page after the first under <dir>/page/N/index.html.
+type staticListing struct {
+	dir     string
+	path    string
+	posts   int
+	handler http.HandlerFunc
+}
+
+// staticBuild is set while GenerateStaticSite runs, so pages leave out
+// features that need the server, such as search and the theme form, and link
+// listing pages by path instead of by query.
+var staticBuild bool
+
+// staticDirName reports whether name, a tag, author, or series of the given
+// kind, can be written as a single directory of the static site, the path
+// segment its page is served under. Names that would leave their directory or
+// nest inside it, such as "../x" or "a/b", are logged and get no page.
+func staticDirName(kind, name string) bool {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		logWarn("Skipping the page of %s %q, which is not usable as a path segment", kind, name)
+		return false
+	}
+	return true
 }
 
 // GenerateStaticSite generates static HTML files for all pages
@@ -142,54 +678,192 @@ func GenerateStaticSite(outputDir string) error {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return err
 	}
+	staticBuild = true
+	defer func() { staticBuild = false }()
 
 	// Generate index.html (home page)
 	if err := generatePage(outputDir, "index.html", func(w http.ResponseWriter) error {
-		HomeHandler(w, &http.Request{})
+		HomeHandler(w, newRequest("/"))
 		return nil
 	}); err != nil {
 		return err
 	}
 
 	// Generate about.html
-	if err := generatePage(outputDir, "about.html", func(w http.ResponseWriter) error {
-		AboutHandler(w, &http.Request{})
-		return nil
-	}); err != nil {
-		return err
+	if navPages.HasAbout {
+		if err := generatePage(outputDir, "about.html", func(w http.ResponseWriter) error {
+			AboutHandler(w, newRequest("/about"))
+			return nil
+		}); err != nil {
+			return err
+		}
 	}
 
 	// Generate contact.html
-	if err := generatePage(outputDir, "contact.html", func(w http.ResponseWriter) error {
-		ContactHandler(w, &http.Request{})
+	if navPages.HasContact {
+		if err := generatePage(outputDir, "contact.html", func(w http.ResponseWriter) error {
+			ContactHandler(w, newRequest("/contact"))
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Generate 404.html, which GitHub Pages serves for unknown paths
+	if err := generatePage(outputDir, "404.html", func(w http.ResponseWriter) error {
+		NotFoundHandler(w, newRequest("/404"))
 		return nil
 	}); err != nil {
 		return err
 	}
 
-	// Generate post pages
-	files, err := filepath.Glob("posts/*.md")
+	// Generate post pages, leaving out drafts
+	posts, err := LoadBlogPosts()
 	if err != nil {
 		return err
 	}
 
-	for _, file := range files {
-		slug := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	notes, err := LoadSectionPosts(notesSection)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range append(posts, notes...) {
 		// Generate as post/slug/index.html for GitHub Pages clean URLs
-		postPath := filepath.Join("post", slug, "index.html")
-		reqURL, _ := url.Parse("/post/" + slug)
-		req := &http.Request{
-			URL: reqURL,
+		postPath := filepath.Join(filepath.FromSlash(strings.TrimPrefix(post.rawPath(), "/")), "index.html")
+		req := newRequest(post.escapedPath())
+		handler := PostHandler
+		if post.Section == notesSection.Name {
+			handler = NoteHandler
+		} else if usesDatedPermalinks() {
+			handler = DatedPostHandler
 		}
 		if err := generatePage(outputDir, postPath, func(w http.ResponseWriter) error {
-			PostHandler(w, req)
+			handler(w, req)
 			return nil
 		}); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	// Generate the feeds, the sitemap, and the listing pages linked from the nav
+	pages := []staticPage{
+		{"feed.xml", "/feed.xml", FeedHandler},
+		{"atom.xml", "/atom.xml", AtomHandler},
+		{"feed.json", "/feed.json", JSONFeedHandler},
+		{"sitemap.xml", "/sitemap.xml", SitemapHandler},
+		{"robots.txt", "/robots.txt", RobotsHandler},
+		{"site.webmanifest", "/site.webmanifest", ManifestHandler},
+		{"archive.html", "/archive", ArchiveHandler},
+		{"tags.html", "/tags", TagsHandler},
+		{"notes.html", "/notes", NotesHandler},
+	}
+	var tags []TagCount
+	for _, tag := range countTags(posts) {
+		if staticDirName("tag", tag.Name) {
+			tags = append(tags, tag)
+		}
+	}
+	var authors []string
+	for _, author := range authorNames(posts) {
+		if staticDirName("author", author) {
+			authors = append(authors, author)
+		}
+	}
+	for _, tag := range tags {
+		pages = append(pages, staticPage{filepath.Join("tags", tag.Name, "index.html"), "/tags/" + tag.Name, TagHandler})
+	}
+	for _, author := range authors {
+		pages = append(pages, staticPage{filepath.Join("authors", author, "index.html"), "/authors/" + author, AuthorHandler})
+	}
+	for _, series := range seriesNames(posts) {
+		if staticDirName("series", series) {
+			pages = append(pages, staticPage{filepath.Join("series", series, "index.html"), "/series/" + series, SeriesHandler})
+		}
+	}
+	if hasFavicon() {
+		pages = append(pages, staticPage{"favicon.ico", "/favicon.ico", FaviconHandler})
+	}
+	for _, page := range pages {
+		req := newRequest(page.path)
+		if err := generatePage(outputDir, page.filename, func(w http.ResponseWriter) error {
+			page.handler(w, req)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Generate the later pages of the listings, where listingPageLink points
+	listings := []staticListing{
+		{"", "/", len(posts), HomeHandler},
+		{"notes", "/notes", len(notes), NotesHandler},
+	}
+	for _, tag := range tags {
+		listings = append(listings, staticListing{filepath.Join("tags", tag.Name), "/tags/" + tag.Name, tag.Count, TagHandler})
+	}
+	for _, author := range authors {
+		written := 0
+		for _, post := range posts {
+			if strings.EqualFold(post.Author, author) {
+				written++
+			}
+		}
+		listings = append(listings, staticListing{filepath.Join("authors", author), "/authors/" + author, written, AuthorHandler})
+	}
+	for _, listing := range listings {
+		for page := 2; page <= pageCount(listing.posts); page++ {
+			req := newRequest(listing.path)
+			req.URL.RawQuery = "page=" + strconv.Itoa(page)
+			filename := filepath.Join(listing.dir, "page", strconv.Itoa(page), "index.html")
+			if err := generatePage(outputDir, filename, func(w http.ResponseWriter) error {
+				listing.handler(w, req)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Copy static assets as they are, then add the generated stylesheet
+	if err := copyDir(staticDir, filepath.Join(outputDir, staticDir)); err != nil {
+		return err
+	}
+	return generatePage(outputDir, filepath.FromSlash(strings.TrimPrefix(highlightCSSPath, "/")), func(w http.ResponseWriter) error {
+		HighlightCSSHandler(w, newRequest(highlightCSSPath))
+		return nil
+	})
+}
+
+// copyDir copies the files under src in siteFS into dst on disk, creating
+// directories as needed. A missing src is not an error.
+func copyDir(src, dst string) error {
+	if _, err := fs.Stat(siteFS, src); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return fs.WalkDir(siteFS, src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := fs.ReadFile(siteFS, path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
+
+// newRequest builds a GET request for path, used to render pages offline.
+func newRequest(path string) *http.Request {
+	return &http.Request{Method: http.MethodGet, URL: &url.URL{Path: path}}
 }
 
 // generatePage generates a single HTML page by executing a handler
@@ -236,120 +910,447 @@ type responseWriter struct {
 	http.ResponseWriter
 }
 
+// notModified reports whether the client's cached copy, described by the
+// If-None-Match or If-Modified-Since request headers, is still current.
+// If-None-Match takes precedence when both are present.
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil {
+		// HTTP dates have second precision
+		return !modTime.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// PostPage is the data passed to the post template.
+type PostPage struct {
+	PostData
+	URL       string // Absolute URL of the post, used for link previews
+	ImageURL  string // Absolute URL of the link preview image, if any
+	Canonical string // Same as URL, whichever path served the post
+	Theme     string
+	Related   []PostData
+	InSeries  *SeriesInfo     // Position within the post's series, nil outside one
+	Views     int             // Times the post has been viewed, 0 when not counted
+	Article   articleLD       // schema.org metadata for search engines
+	Comments  *CommentsConfig // Comment embed settings, nil when comments are off
+	CommentID string          // Identifies the post's comment thread
+	Mentions  []Mention       // Webmentions received for the post
+	Newer     *PostData       // Chronologically previous post, nil for the newest
+	Older     *PostData       // Chronologically next post, nil for the oldest
+}
+
+// adjacentPosts returns the posts published right before and after post,
+// ignoring weights. Either is nil when post is at that end of the list or not
+// listed at all, as with previewed drafts.
+func adjacentPosts(post PostData, posts []PostData) (newer, older *PostData) {
+	sorted := newestFirst(posts)
+	for i := range sorted {
+		if sorted[i].Slug != post.Slug {
+			continue
+		}
+		if i > 0 {
+			newer = &sorted[i-1]
+		}
+		if i+1 < len(sorted) {
+			older = &sorted[i+1]
+		}
+		break
+	}
+	return newer, older
+}
+
+// PostHandler serves a post from the posts section. With dated permalinks,
+// /post/<slug> either redirects to the dated URL or serves the post as an
+// alias, depending on redirect_post_urls.
 func PostHandler(w http.ResponseWriter, r *http.Request) {
-	slug := r.URL.Path[len("/post/"):]
-	post, err := LoadPost(slug)
+	if usesDatedPermalinks() && config.RedirectPostURLs {
+		name := strings.TrimPrefix(r.URL.Path, postsSection.Prefix())
+		post, err := LoadSectionPost(postsSection, strings.TrimSuffix(name, ".md"))
+		if err != nil {
+			postLoadError(w, r, err)
+			return
+		}
+		target := post.Path()
+		if strings.HasSuffix(name, ".md") {
+			target = post.SourcePath()
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+	serveSectionPost(w, r, postsSection)
+}
+
+// NoteHandler serves a post from the notes section.
+func NoteHandler(w http.ResponseWriter, r *http.Request) {
+	serveSectionPost(w, r, notesSection)
+}
+
+// serveSectionPost renders the post of the section named by the request path.
+// A slug ending in .md, or an Accept header asking for text/markdown, gets the
+// Markdown source instead.
+func serveSectionPost(w http.ResponseWriter, r *http.Request, section Section) {
+	slug, source := sourceSlug(strings.TrimPrefix(r.URL.Path, section.Prefix()), r)
+	post, err := LoadSectionPost(section, slug)
 	if err != nil {
-		http.Error(w, "Post not found", http.StatusNotFound)
+		postLoadError(w, r, err)
 		return
 	}
-	// data := struct {
-	// 	Title string
-	// 	Post  PostData
-	// }{
-	// 	Title: post.Title,
-	// 	Post:  post,
-	// }
-	// fmt.Println(data)
-
-	tmpl := template.Must(template.ParseFiles(filepath.Join("templates", "base.gohtml"), filepath.Join("templates", "post.gohtml")))
-	if err := tmpl.Execute(w, post); err != nil {
-		http.Error(w, "Error executing template", http.StatusInternalServerError)
+	if source {
+		servePostSource(w, post)
 		return
 	}
+	renderPost(w, r, section, post)
 }
 
-func LoadPost(slug string) (PostData, error) {
-	// Find the Markdown file with the given slug
-	file := filepath.Join("posts", slug+".md")
-	if _,
-		err := os.Stat(file); os.IsNotExist(err) {
-		return PostData{}, err
+// postLoadError answers a request for a post that could not be loaded: 404
+// when it does not exist, 500 when its file or another post is broken.
+func postLoadError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		NotFoundHandler(w, r)
+		return
 	}
+	InternalErrorHandler(w, r, err)
+}
 
-	// Load and convert the Markdown content to HTML
-	content, err := RenderMarkdown(file)
-	if err != nil {
-		return PostData{}, err
+// sourceSlug strips a .md extension from slug and reports whether the
+// Markdown source of the post was requested, either through the extension or
+// an Accept header preferring text/markdown.
+func sourceSlug(slug string, r *http.Request) (string, bool) {
+	if trimmed := strings.TrimSuffix(slug, ".md"); trimmed != slug {
+		return trimmed, true
 	}
+	return slug, strings.HasPrefix(r.Header.Get("Accept"), "text/markdown")
+}
 
-	// Extract the filename without the extension to use as the Title
-	filename := filepath.Base(file)
-	title := CleanTitle(filename)
+// servePostSource writes the Markdown body of a post, without its frontmatter.
+func servePostSource(w http.ResponseWriter, post PostData) {
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	w.Write(post.source)
+}
 
-	// Use the file's ModTime as the post's Date
-	fileInfo, err := os.Stat(file)
+// renderPost renders a post of the section, answering conditional requests
+// with 304 Not Modified when the rendered page has not changed.
+func renderPost(w http.ResponseWriter, r *http.Request, section Section, post PostData) {
+	layout := postLayout(post)
+	if printView(r) {
+		layout = printLayout
+	}
+
+	posts, err := LoadSectionPosts(section)
 	if err != nil {
-		return PostData{}, err
+		InternalErrorHandler(w, r, err)
+		return
+	}
+
+	data := PostPage{
+		PostData: post,
+		URL:      BaseURL() + post.Path(),
+		Related:  relatedPosts(post, posts),
+		InSeries: seriesInfo(post, posts),
+	}
+	data.Canonical = data.URL
+	data.Theme = themeFor(r)
+	data.ImageURL = previewImageURL(post)
+	data.Article = newArticleLD(post, data.URL, data.ImageURL)
+	data.Newer, data.Older = adjacentPosts(post, posts)
+	data.Comments = postComments(post)
+	data.CommentID = viewKey(post)
+	data.Mentions = mentions.For(viewKey(post))
+
+	// Hash the page as rendered without its view count, so anything else
+	// shown on it, from the neighbouring posts to the theme, changes the ETag
+	// while each view does not. The page also lists the other posts of the
+	// section, so it is as new as the newest of them.
+	var page bytes.Buffer
+	if err := renderTemplate(&page, layout, data); err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+	sum := sha256.Sum256(page.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	modTime := lastUpdated(posts)
+	if post.Updated.After(modTime) {
+		modTime = post.Updated
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Cookie")
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	if notModified(r, etag, modTime) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// Count the view only now, so revalidations are not counted, and render
+	// the page again with the count. Previewed drafts and scheduled posts are
+	// not counted.
+	if !post.Draft && !post.Scheduled() {
+		data.Views = views.Add(viewKey(post))
+	}
+	if data.Views > 0 {
+		page.Reset()
+		if err := renderTemplate(&page, layout, data); err != nil {
+			InternalErrorHandler(w, r, err)
+			return
+		}
+	}
+	page.WriteTo(w)
+}
+
+// printLayout is the template of the print view of a post, a minimal page
+// without nav or footer.
+const printLayout = "print"
+
+// printView reports whether r asks for the print view with ?print=1.
+func printView(r *http.Request) bool {
+	on, _ := strconv.ParseBool(r.URL.Query().Get("print"))
+	return on
+}
+
+// checkLayout reports whether layout, as set in a post's frontmatter, names a
+// page template in templates/. The base layout, standalone pages such as the
+// print view, and names that are not plain file names cannot be chosen.
+func checkLayout(layout string) error {
+	if layout == "base" || standaloneTemplates[layout] || strings.ContainsAny(layout, `/\.`) {
+		return fmt.Errorf("layout %q cannot be used for posts", layout)
+	}
+	if _, err := fs.Stat(siteFS, "templates/"+layout+".gohtml"); err != nil {
+		return fmt.Errorf("no templates/%s.gohtml", layout)
+	}
+	return nil
+}
+
+// warnedLayouts remembers which posts have been reported for an unusable
+// layout so the warning is logged once rather than on every request.
+var warnedLayouts sync.Map
+
+// postLayout returns the template the post is rendered with: the layout named
+// in its frontmatter when checkLayout accepts it, and "post" otherwise.
+// Layouts receive the same PostPage data as post.gohtml.
+func postLayout(post PostData) string {
+	if post.Layout == "" {
+		return "post"
+	}
+	if err := checkLayout(post.Layout); err != nil {
+		if _, warned := warnedLayouts.LoadOrStore(viewKey(post)+":"+post.Layout, true); !warned {
+			logWarn("Post %q: %v, using post", viewKey(post), err)
+		}
+		return "post"
+	}
+	return post.Layout
+}
+
+// LoadPost loads a single post from the posts section by slug.
+func LoadPost(slug string) (PostData, error) {
+	return LoadSectionPost(postsSection, slug)
+}
+
+// paginate returns the posts on the given page along with the page number,
+// clamped to the valid range, and the total number of pages.
+func paginate(posts []PostData, page int) ([]PostData, int, int) {
+	totalPages := pageCount(len(posts))
+	if page < 1 {
+		page = 1
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+
+	start := (page - 1) * postsPerPage
+	end := start + postsPerPage
+	if end > len(posts) {
+		end = len(posts)
 	}
+	return posts[start:end], page, totalPages
+}
 
-	// Create a Post object
-	post := PostData{
-		Title:   title,
-		Date:    fileInfo.ModTime(),
-		Slug:    slug,
-		Content: content,
+// pageCount returns the number of listing pages needed for n posts, at least
+// one.
+func pageCount(n int) int {
+	if n <= 0 {
+		return 1
 	}
+	return (n + postsPerPage - 1) / postsPerPage
+}
 
-	return post, nil
+// listingPageLink returns the link to a page of the listing at path. The
+// first page has no query so it matches the listing's canonical URL. In
+// static builds later pages are files under <path>/page/N/.
+func listingPageLink(path string, page int) string {
+	if page <= 1 {
+		return path
+	}
+	if staticBuild {
+		return slashPath(strings.TrimSuffix(path, "/") + "/page/" + strconv.Itoa(page))
+	}
+	return path + "?page=" + strconv.Itoa(page)
+}
+
+// newListingData returns the template data of a post listing, showing the
+// page of posts selected by the ?page= query of r along with the links to the
+// pages around it.
+func newListingData(r *http.Request, title string, posts []PostData) TemplateData {
+	page := 1
+	if n, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil {
+		page = n
+	}
+	posts, page, totalPages := paginate(posts, page)
+	path := r.URL.EscapedPath()
+
+	data := TemplateData{
+		Title: title,
+		// Later pages list other posts, so each is its own canonical page
+		Canonical:   BaseURL() + listingPageLink(slashPath(path), page),
+		Theme:       themeFor(r),
+		Posts:       posts,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+		HasPrev:     page > 1,
+		HasNext:     page < totalPages,
+		PrevPage:    page - 1,
+		NextPage:    page + 1,
+	}
+	if data.HasPrev {
+		data.PrevLink = listingPageLink(path, data.PrevPage)
+		data.PrevURL = BaseURL() + data.PrevLink
+	}
+	if data.HasNext {
+		data.NextLink = listingPageLink(path, data.NextPage)
+		data.NextURL = BaseURL() + data.NextLink
+	}
+	return data
 }
 
 // HomeHandler renders the home page with blog posts.
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
+	// "/" matches every path without a more specific route
+	if r.URL.Path != "/" {
+		if usesDatedPermalinks() && datedPath.MatchString(r.URL.Path) {
+			DatedPostHandler(w, r)
+			return
+		}
+		NotFoundHandler(w, r)
+		return
+	}
+
 	posts, err := LoadBlogPosts()
 	if err != nil {
-		log.Fatal(err)
+		InternalErrorHandler(w, r, err)
+		return
 	}
 
-	data := TemplateData{
-		Title: "My Blog",
-		Posts: posts,
-	}
+	data := newListingData(r, config.SiteTitle, posts)
 
-	tmpl := template.Must(template.ParseFiles(filepath.Join("templates", "base.gohtml"), filepath.Join("templates", "home.gohtml")))
-	if err := tmpl.Execute(w, data); err != nil {
-		log.Fatal(err)
+	if err := renderTemplate(w, "home", data); err != nil {
+		InternalErrorHandler(w, r, err)
+		return
 	}
 }
 
-// AboutHandler serves the About page.
 // AboutHandler serves the About page.
 func AboutHandler(w http.ResponseWriter, r *http.Request) {
-	content, err := RenderMarkdown("nav/about.md")
+	content, err := RenderMarkdown(aboutFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		NotFoundHandler(w, r)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error loading about page", http.StatusInternalServerError)
+		InternalErrorHandler(w, r, err)
+		return
+	}
+	data := struct {
+		Title     string
+		Canonical string
+		Theme     string
+		Content   template.HTML
+	}{
+		Title:     "About Me",
+		Canonical: canonicalURL(r),
+		Theme:     themeFor(r),
+		Content:   content,
+	}
+	if err := renderTemplate(w, "about", data); err != nil {
+		InternalErrorHandler(w, r, err)
 		return
 	}
+}
+
+// NotFoundHandler renders the 404 page.
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Title     string
+		Canonical string // Left empty, error pages have no canonical URL
+		Theme     string
+		Path      string
+	}{
+		Title: "Page Not Found",
+		Theme: themeFor(r),
+		Path:  r.URL.Path,
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	if err := renderTemplate(w, "404", data); err != nil {
+		logError("Error executing 404 template: %v", err)
+	}
+}
+
+// InternalErrorHandler logs err and renders the 500 page. It falls back to a
+// plain text response when the error page itself cannot be rendered.
+func InternalErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	logRequestError(r, err)
+
 	data := struct {
-		Title   string
-		Content template.HTML
+		Title     string
+		Canonical string
+		Theme     string
 	}{
-		Title:   "About Me",
-		Content: content,
+		Title: "Internal Server Error",
+		Theme: themeFor(r),
 	}
-	tmpl := template.Must(template.ParseFiles(filepath.Join("templates", "base.gohtml"), filepath.Join("templates", "about.gohtml")))
-	if err := tmpl.Execute(w, data); err != nil {
-		http.Error(w, "Error executing template", http.StatusInternalServerError)
+	var buf bytes.Buffer
+	if err := renderTemplate(&buf, "500", data); err != nil {
+		logError("Error executing 500 template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write(buf.Bytes())
 }
 
 func ContactHandler(w http.ResponseWriter, r *http.Request) {
-	content, err := RenderMarkdown("nav/contact.md")
+	content, err := RenderMarkdown(contactFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		NotFoundHandler(w, r)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error loading contact page", http.StatusInternalServerError)
+		InternalErrorHandler(w, r, err)
 		return
 	}
 	data := struct {
-		Title   string
-		Content template.HTML
+		Title     string
+		Canonical string
+		Theme     string
+		Content   template.HTML
 	}{
-		Title:   "Contact Me",
-		Content: content,
+		Title:     "Contact Me",
+		Canonical: canonicalURL(r),
+		Theme:     themeFor(r),
+		Content:   content,
 	}
-	tmpl := template.Must(template.ParseFiles(filepath.Join("templates", "base.gohtml"), filepath.Join("templates", "contact.gohtml")))
-	if err := tmpl.Execute(w, data); err != nil {
-		http.Error(w, "Error executing template", http.StatusInternalServerError)
+	if err := renderTemplate(w, "contact", data); err != nil {
+		InternalErrorHandler(w, r, err)
 		return
 	}
 }
@@ -0,0 +1,745 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRenderMarkdownBrokenFrontmatter(t *testing.T) {
+	siteFS = fstest.MapFS{
+		"broken.md": {Data: []byte("---\ntitle: [unclosed\ndate: 2024-01-05\n---\n# Hello\n")},
+	}
+	t.Cleanup(func() { siteFS = os.DirFS(".") })
+
+	if _, err := RenderMarkdown("broken.md"); err == nil {
+		t.Fatal("expected an error for malformed frontmatter, got nil")
+	}
+}
+
+func TestConvertMarkdownGFM(t *testing.T) {
+	md := "| Name | Value |\n| ---- | ----- |\n| a    | 1     |\n\n~~gone~~\n"
+	content, _, err := convertMarkdown([]byte(md))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"<table>", "<del>gone</del>"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("expected %q in output, got:\n%s", want, content)
+		}
+	}
+}
+
+func TestConvertMarkdownFootnotes(t *testing.T) {
+	md := "Finalizers block deletion.[^1]\n\n[^1]: Until the controller removes them.\n"
+	content, _, err := convertMarkdown([]byte(md))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup>`,
+		`<li id="fn:1">`,
+		"Until the controller removes them.",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("expected %q in output, got:\n%s", want, content)
+		}
+	}
+}
+
+func TestConvertMarkdownDefinitionList(t *testing.T) {
+	md := "Finalizer\n: A key that blocks deletion.\n\nA plain paragraph.\nWith a second line: not a term.\n"
+	content, _, err := convertMarkdown([]byte(md))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"<dl>",
+		"<dt>Finalizer</dt>",
+		"<dd>A key that blocks deletion.</dd>",
+		"<p>A plain paragraph.\nWith a second line: not a term.</p>",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("expected %q in output, got:\n%s", want, content)
+		}
+	}
+	if n := strings.Count(string(content), "<dt>"); n != 1 {
+		t.Errorf("expected 1 term, got %d:\n%s", n, content)
+	}
+}
+
+func TestConvertMarkdownEmoji(t *testing.T) {
+	md := "Shipped :rocket:\n\nRun `echo :rocket:` to celebrate.\n\n```\n:rocket:\n```\n"
+	content, _, err := convertMarkdown([]byte(md))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(content), "Shipped \U0001F680") {
+		t.Errorf("expected the shortcode to become an emoji, got:\n%s", content)
+	}
+	if !strings.Contains(string(content), "<code>echo :rocket:</code>") {
+		t.Errorf("expected the shortcode in inline code to stay literal, got:\n%s", content)
+	}
+	if strings.Count(string(content), ":rocket:") != 2 {
+		t.Errorf("expected the shortcode in the code block to stay literal, got:\n%s", content)
+	}
+}
+
+func TestConvertMarkdownSharedRendererMatchesFresh(t *testing.T) {
+	_, body, err := ReadMarkdown(filepath.Join("posts", "finalizer.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Render the post the way it was done before, with a converter per call
+	var want bytes.Buffer
+	if err := newMarkdown().Convert(body, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	// Convert twice so state left behind by a previous call would show up
+	for i := 0; i < 2; i++ {
+		got, _, err := convertMarkdown(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want.String() {
+			t.Fatalf("conversion %d differs from a fresh converter:\ngot:\n%s\nwant:\n%s", i+1, got, want.String())
+		}
+	}
+}
+
+func TestLoadPostRejectsUnsafeSlugs(t *testing.T) {
+	slugs := []string{
+		"",
+		"../main",
+		"../../etc/passwd",
+		"..%2F..%2Fetc%2Fpasswd",
+		"finalizer/../finalizer",
+		"/etc/passwd",
+		filepath.Join(t.TempDir(), "post"),
+		"finalizer.md",
+		"finalizer\x00",
+	}
+	for _, slug := range slugs {
+		if _, err := LoadPost(slug); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("LoadPost(%q) error = %v, want os.ErrNotExist", slug, err)
+		}
+	}
+}
+
+func TestLoadPostValidSlug(t *testing.T) {
+	post, err := LoadPost("finalizer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Slug != "finalizer" {
+		t.Errorf("post.Slug = %q, want %q", post.Slug, "finalizer")
+	}
+}
+
+func TestWordAndCharCount(t *testing.T) {
+	src := []byte("# Über uns\n\nHello, world!  Two\tlines\nhere.\n\n```go\nfmt.Println(\"skipped\")\n```\n\n~~~\nalso skipped\n~~~\n")
+	if got := wordCount(src); got != 8 {
+		t.Errorf("wordCount = %d, want 8", got)
+	}
+	// "#", "Über", "uns", "Hello,", "world!", "Two", "lines", "here." without whitespace
+	if got := charCount(src); got != 33 {
+		t.Errorf("charCount = %d, want 33", got)
+	}
+}
+
+func TestSortPostsWeight(t *testing.T) {
+	weight := func(n int) *int { return &n }
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	posts := []PostData{
+		{Slug: "old", Date: day(1)},
+		{Slug: "heavy", Date: day(2), Weight: weight(20)},
+		{Slug: "new", Date: day(9)},
+		{Slug: "light-old", Date: day(3), Weight: weight(10)},
+		{Slug: "light-new", Date: day(4), Weight: weight(10)},
+		{Slug: "middle", Date: day(5)},
+	}
+
+	sortPosts(posts)
+
+	want := []string{"light-new", "light-old", "heavy", "new", "middle", "old"}
+	for i, post := range posts {
+		if post.Slug != want[i] {
+			t.Fatalf("position %d: got %q, want %q (full order %v)", i, post.Slug, want[i], slugsOf(posts))
+		}
+	}
+}
+
+func slugsOf(posts []PostData) []string {
+	var slugs []string
+	for _, post := range posts {
+		slugs = append(slugs, post.Slug)
+	}
+	return slugs
+}
+
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		path    string
+		handler http.HandlerFunc
+		status  int
+		body    string
+	}{
+		{"/api/post/no-such-post", APIPostHandler, http.StatusNotFound, `{"error":"not found"}`},
+		{"/api/posts?limit=abc", APIPostsHandler, http.StatusBadRequest, `{"error":"invalid limit"}`},
+		{"/api/stats?drafts=maybe", APIStatsHandler, http.StatusBadRequest, `{"error":"invalid drafts"}`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("GET %s status = %d, want %d", tt.path, rec.Code, tt.status)
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("GET %s Content-Type = %q, want application/json", tt.path, got)
+		}
+		if got := strings.TrimSpace(rec.Body.String()); got != tt.body {
+			t.Errorf("GET %s body = %s, want %s", tt.path, got, tt.body)
+		}
+	}
+}
+
+// useContentDir serves posts from a temporary content directory holding
+// files, accepting the given post patterns.
+func useContentDir(t *testing.T, patterns []string, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved := config
+	contentDir = dir
+	config.PostPatterns = patterns
+	postCache.Clear()
+	t.Cleanup(func() {
+		contentDir = ""
+		config = saved
+		postCache.Clear()
+	})
+}
+
+func TestLoadPostMarkdownExtensions(t *testing.T) {
+	useContentDir(t, []string{"*.md", "*.markdown"}, map[string]string{
+		"posts/long.markdown": "---\ntitle: Long Extension\ndate: 2024-01-05\n---\nLoaded from .markdown\n",
+		"posts/short.md":      "---\ntitle: Short Extension\ndate: 2024-01-04\n---\nLoaded from .md\n",
+		"posts/other.mdown":   "---\ntitle: Not Matched\ndate: 2024-01-03\n---\nSkipped\n",
+	})
+
+	post, err := LoadPost("long")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Long Extension" {
+		t.Errorf("post.Title = %q, want %q", post.Title, "Long Extension")
+	}
+	if _, err := LoadPost("other"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadPost(%q) error = %v, want os.ErrNotExist", "other", err)
+	}
+
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := slugsOf(posts); len(got) != 2 || got[0] != "long" || got[1] != "short" {
+		t.Errorf("LoadBlogPosts slugs = %v, want [long short]", got)
+	}
+}
+
+func TestServePostMarkdownExtension(t *testing.T) {
+	useContentDir(t, []string{"*.md", "*.markdown"}, map[string]string{
+		"posts/long.markdown": "---\ntitle: Long Extension\ndate: 2024-01-05\n---\nServed from .markdown\n",
+	})
+
+	rec := httptest.NewRecorder()
+	PostHandler(rec, httptest.NewRequest(http.MethodGet, "/post/long", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /post/long status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), "<p>Served from .markdown</p>") {
+		t.Errorf("expected the post content in the page, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestServePostBrokenFrontmatter(t *testing.T) {
+	useContentDir(t, []string{"*.md"}, map[string]string{
+		"posts/good.md":   "---\ntitle: Good\ndate: 2024-01-05\n---\nFine\n",
+		"posts/broken.md": "---\ntitle: [unclosed\n---\nBroken\n",
+	})
+
+	tests := []struct {
+		path    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{"/post/good", PostHandler, http.StatusOK},
+		{"/", HomeHandler, http.StatusOK},
+		{"/feed.xml", FeedHandler, http.StatusOK},
+		{"/post/missing", PostHandler, http.StatusNotFound},
+		{"/post/broken", PostHandler, http.StatusInternalServerError},
+		{"/api/post/good", APIPostHandler, http.StatusOK},
+		{"/api/post/broken", APIPostHandler, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("GET %s status = %d, want %d", tt.path, rec.Code, tt.status)
+		}
+	}
+
+	if _, _, err := ReadMarkdown("posts/broken.md"); err == nil || !strings.Contains(err.Error(), "posts/broken.md") {
+		t.Errorf("ReadMarkdown error = %v, want one naming posts/broken.md", err)
+	}
+}
+
+func TestPostETagCoversNeighbours(t *testing.T) {
+	useContentDir(t, []string{"*.md"}, map[string]string{
+		"posts/a.md": "---\ntitle: A\ndate: 2024-01-01\n---\nFirst\n",
+		"posts/b.md": "---\ntitle: B\ndate: 2024-01-02\n---\nSecond\n",
+	})
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/post/b", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		PostHandler(rec, req)
+		return rec
+	}
+
+	etag := get("").Header().Get("ETag")
+	if rec := get(etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("unchanged page status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+
+	// A newer post adds a link to it on b
+	file := filepath.Join(contentDir, "posts", "c.md")
+	if err := os.WriteFile(file, []byte("---\ntitle: C\ndate: 2024-01-03\n---\nThird\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec := get(etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status after adding a newer post = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), `href="/post/c"`) {
+		t.Errorf("expected a link to the newer post, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestRelatedPostsIgnoresWeight(t *testing.T) {
+	weight := func(n int) *int { return &n }
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	posts := []PostData{
+		{Slug: "pinned", Date: day(1), Weight: weight(1)},
+		{Slug: "newest", Date: day(9)},
+		{Slug: "middle", Date: day(5)},
+	}
+	sortPosts(posts)
+
+	got := slugsOf(relatedPosts(PostData{Slug: "untagged"}, posts))
+	want := []string{"newest", "middle", "pinned"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("relatedPosts = %v, want %v", got, want)
+	}
+}
+
+func TestPostLinksFromNestedPost(t *testing.T) {
+	useContentDir(t, []string{"*.md"}, map[string]string{
+		"posts/a.md":        "---\ntitle: A\ndate: 2024-01-01\n---\nTop level\n",
+		"posts/go/other.md": "---\ntitle: Other\ndate: 2024-01-02\n---\nSibling\n",
+		"posts/go/ch.md":    "---\ntitle: Channels\ndate: 2024-01-03\n---\nSee [other](other.md#setup), [a](../a.md) and [gone](gone.md).\n",
+	})
+
+	post, err := LoadPost("go/ch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<a href="/post/go/other#setup">other</a>`,
+		`<a href="/post/a">a</a>`,
+		`<a href="gone.md">gone</a>`,
+	} {
+		if !strings.Contains(string(post.Content), want) {
+			t.Errorf("expected %q in output, got:\n%s", want, post.Content)
+		}
+	}
+}
+
+func TestLoadPostFileNeedsDateWithoutModTime(t *testing.T) {
+	// Like embedded files, MapFS entries have no ModTime unless set
+	siteFS = fstest.MapFS{
+		"posts/undated.md": {Data: []byte("# No date\n")},
+	}
+	t.Cleanup(func() { siteFS = os.DirFS(".") })
+
+	if _, err := loadPostFile("posts/undated.md"); err == nil {
+		t.Fatal("expected an error for a post without a date or ModTime, got nil")
+	}
+}
+
+func TestGenerateStaticSitePaginates(t *testing.T) {
+	files := make(map[string]string)
+	for i := 1; i <= postsPerPage+1; i++ {
+		files[fmt.Sprintf("posts/p%d.md", i)] = fmt.Sprintf("---\ntitle: P%d\ndate: 2024-01-%02d\n---\nBody\n", i, i)
+	}
+	useContentDir(t, []string{"*.md"}, files)
+
+	out := t.TempDir()
+	if err := GenerateStaticSite(out); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "page", "2", "index.html")); err != nil {
+		t.Fatalf("second home page not generated: %v", err)
+	}
+	home, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(home), `href="/page/2"`) {
+		t.Errorf("expected a link to /page/2 in index.html, got:\n%s", home)
+	}
+	for _, absent := range []string{"?page=", `href="/search"`, `action="/theme"`} {
+		if strings.Contains(string(home), absent) {
+			t.Errorf("index.html links to %s, which the static site does not serve", absent)
+		}
+	}
+}
+
+func TestPostLoadsKaTeXOnlyWithMath(t *testing.T) {
+	useContentDir(t, []string{"*.md"}, map[string]string{
+		"posts/math.md":  "---\ntitle: Math\ndate: 2024-01-01\n---\nEuler: $e^{i\\pi} = -1$\n",
+		"posts/plain.md": "---\ntitle: Plain\ndate: 2024-01-02\n---\nIt costs $5.\n",
+	})
+	for _, tt := range []struct {
+		path  string
+		katex bool
+	}{
+		{"/post/math", true},
+		{"/post/plain", false},
+	} {
+		rec := httptest.NewRecorder()
+		PostHandler(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := strings.Contains(rec.Body.String(), "katex.min.js"); got != tt.katex {
+			t.Errorf("GET %s loads KaTeX = %v, want %v", tt.path, got, tt.katex)
+		}
+	}
+}
+
+func TestMentionStoreLimit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "webmentions.json")
+	store, err := LoadMentionStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxMentionsPerPost; i++ {
+		if err := store.Add("post", Mention{Source: fmt.Sprintf("https://example.com/%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Add("post", Mention{Source: "https://example.com/new"}); !errors.Is(err, errTooManyMentions) {
+		t.Errorf("Add beyond the limit = %v, want %v", err, errTooManyMentions)
+	}
+	// A repeated notification from a known source is still accepted
+	if err := store.Add("post", Mention{Source: "https://example.com/0"}); err != nil {
+		t.Errorf("Add of a known source = %v, want nil", err)
+	}
+}
+
+func TestPostUnknownLayoutFallsBack(t *testing.T) {
+	useContentDir(t, []string{"*.md"}, map[string]string{
+		"posts/typo.md":  "---\ntitle: Typo\ndate: 2024-01-01\nlayout: psot\n---\nTypo body\n",
+		"posts/print.md": "---\ntitle: Print\ndate: 2024-01-02\nlayout: print\n---\nPrint body\n",
+	})
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	for _, slug := range []string{"typo", "print", "typo"} {
+		rec := httptest.NewRecorder()
+		PostHandler(rec, httptest.NewRequest(http.MethodGet, "/post/"+slug, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /post/%s status = %d, want %d", slug, rec.Code, http.StatusOK)
+		}
+		// Rendered with post.gohtml inside the base layout
+		if !strings.Contains(rec.Body.String(), `class="post-content"`) || !strings.Contains(rec.Body.String(), "<nav>") {
+			t.Errorf("GET /post/%s was not rendered with the post layout, got:\n%s", slug, rec.Body.String())
+		}
+	}
+	for _, want := range []string{`layout "print" cannot be used`, "no templates/psot.gohtml"} {
+		if n := strings.Count(logs.String(), want); n != 1 {
+			t.Errorf("warning %q logged %d times, want once; logs:\n%s", want, n, logs.String())
+		}
+	}
+}
+
+func TestLoadRedirectsStatus(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		ok     bool
+	}{
+		{301, true}, {302, true}, {303, true}, {307, true}, {308, true},
+		{300, false}, {304, false}, {305, false}, {306, false},
+	} {
+		file := filepath.Join(t.TempDir(), "redirects.yaml")
+		data := fmt.Sprintf("/old:\n  to: /new\n  status: %d\n", tt.status)
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadRedirects(file); (err == nil) != tt.ok {
+			t.Errorf("LoadRedirects with status %d: err = %v, want ok = %v", tt.status, err, tt.ok)
+		}
+	}
+}
+
+func TestListingCanonicalIncludesPage(t *testing.T) {
+	var posts []PostData
+	for i := 0; i <= postsPerPage; i++ {
+		posts = append(posts, PostData{Slug: fmt.Sprintf("p%d", i)})
+	}
+	for _, tt := range []struct {
+		target string
+		want   string
+	}{
+		{"/tags/go", BaseURL() + "/tags/go"},
+		{"/tags/go?page=1", BaseURL() + "/tags/go"},
+		{"/tags/go?page=2", BaseURL() + "/tags/go?page=2"},
+	} {
+		data := newListingData(httptest.NewRequest(http.MethodGet, tt.target, nil), "Go", posts)
+		if data.Canonical != tt.want {
+			t.Errorf("Canonical for %s = %q, want %q", tt.target, data.Canonical, tt.want)
+		}
+	}
+}
+
+func TestConvertMarkdownSanitized(t *testing.T) {
+	savedMarkdown := markdown
+	sanitizeHTML = true
+	markdown = newMarkdown()
+	t.Cleanup(func() {
+		sanitizeHTML = false
+		markdown = savedMarkdown
+	})
+
+	src := "Inline $x^2$ math[^1] and <a href=\"/x\" onclick=\"steal()\">a link</a>.\n\n" +
+		"![Chart](/static/chart.png \"Monthly chart\")\n\n" +
+		"```go\nfunc main() {}\n```\n\n" +
+		"<script>alert(1)</script>\n\n" +
+		"[^1]: The note.\n"
+	html, _, err := convertMarkdown([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(html)
+	for _, want := range []string{
+		`<pre tabindex="0" class="chroma">`,
+		`<span class="kd">func</span>`,
+		`<span class="math inline">`,
+		`role="doc-noteref"`,
+		`<figcaption>Monthly chart</figcaption>`,
+		`<a href="/x">a link</a>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q to survive sanitizing, got:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"<script", "onclick"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("expected %q to be removed, got:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestPostETagStableWithViews(t *testing.T) {
+	useContentDir(t, []string{"*.md"}, map[string]string{
+		"posts/a.md": "---\ntitle: A\ndate: 2024-01-01\n---\nFirst\n",
+	})
+	counter, err := LoadViewCounter(filepath.Join(t.TempDir(), "views.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	views = counter
+	t.Cleanup(func() { views = nil })
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/post/a", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		PostHandler(rec, req)
+		return rec
+	}
+
+	first := get("")
+	if !strings.Contains(first.Body.String(), "1 view") {
+		t.Errorf("expected the first view to be shown, got:\n%s", first.Body.String())
+	}
+	if rec := get(first.Header().Get("ETag")); rec.Code != http.StatusNotModified {
+		t.Fatalf("conditional GET status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+	if got := views.Counts()["post/a"]; got != 1 {
+		t.Errorf("views after a 304 = %d, want 1", got)
+	}
+}
+
+// hrefPattern finds the link targets in generated pages.
+var hrefPattern = regexp.MustCompile(`href="([^"]*)"`)
+
+func TestGenerateStaticSiteLinksResolve(t *testing.T) {
+	useContentDir(t, []string{"*.md"}, map[string]string{
+		"posts/a.md": "---\ntitle: A\ndate: 2024-01-01\ntags: [go]\n---\nFirst\n",
+		"posts/b.md": "---\ntitle: B\ndate: 2024-01-02\ntags: [go]\n---\nSecond, see [A](a.md)\n",
+	})
+	out := t.TempDir()
+	if err := GenerateStaticSite(out); err != nil {
+		t.Fatal(err)
+	}
+
+	err := filepath.WalkDir(out, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(file) != ".html" {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(out, file)
+		page := "/" + filepath.ToSlash(rel)
+		for _, m := range hrefPattern.FindAllStringSubmatch(string(data), -1) {
+			href := html.UnescapeString(m[1])
+			u, err := url.Parse(href)
+			if err != nil {
+				t.Errorf("%s: bad href %q: %v", rel, href, err)
+				continue
+			}
+			if u.Scheme != "" || u.Host != "" || (u.Path == "" && u.RawQuery == "") {
+				continue
+			}
+			if u.RawQuery != "" {
+				t.Errorf("%s: href %q has a query, which a static host ignores", rel, href)
+				continue
+			}
+			target := u.Path
+			if !strings.HasPrefix(target, "/") {
+				target = path.Join(path.Dir(page), target)
+			}
+			local := filepath.Join(out, filepath.FromSlash(target))
+			found := false
+			for _, candidate := range []string{local, filepath.Join(local, "index.html"), local + ".html"} {
+				if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s: href %q does not resolve inside the output", rel, href)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateStaticSiteUnsafeTagNames(t *testing.T) {
+	useContentDir(t, []string{"*.md"}, map[string]string{
+		"posts/a.md": "---\ntitle: A\ndate: 2024-01-01\ntags: [\"../x\", \"a/b\", ok]\nseries: \"..\"\n---\nBody\n",
+	})
+	out := filepath.Join(t.TempDir(), "out")
+	if err := GenerateStaticSite(out); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "tags", "ok", "index.html")); err != nil {
+		t.Errorf("page of a safe tag not generated: %v", err)
+	}
+	for _, unwanted := range []string{
+		filepath.Join(out, "x"),
+		filepath.Join(out, "tags", "a"),
+		filepath.Join(out, "..", "x"),
+		filepath.Join(out, "series", "index.html"),
+	} {
+		if _, err := os.Stat(unwanted); err == nil {
+			t.Errorf("%s was written for an unsafe name", unwanted)
+		}
+	}
+}
+
+func TestFeedIncludesPostOnceDue(t *testing.T) {
+	useContentDir(t, []string{"*.md"}, map[string]string{
+		"posts/old.md":    "---\ntitle: Old\ndate: 2024-01-01\n---\nPublished\n",
+		"posts/future.md": "---\ntitle: Future\ndate: 2030-01-01T09:00:00Z\n---\nScheduled\n",
+	})
+	feedCacheMu.Lock()
+	feedCache = make(map[string]cachedFeed)
+	feedCacheMu.Unlock()
+	t.Cleanup(func() { now = time.Now })
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		FeedHandler(rec, httptest.NewRequest(http.MethodGet, "/feed.xml", nil))
+		return rec.Body.String()
+	}
+
+	now = func() time.Time { return time.Date(2029, 12, 31, 0, 0, 0, 0, time.UTC) }
+	if feed := get(); strings.Contains(feed, "/post/future") {
+		t.Fatalf("feed lists the scheduled post before its date:\n%s", feed)
+	}
+	now = func() time.Time { return time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC) }
+	if feed := get(); !strings.Contains(feed, "/post/future") {
+		t.Errorf("feed does not list the post once its date has passed:\n%s", feed)
+	}
+}
+
+func TestMermaidFollowsTheme(t *testing.T) {
+	useContentDir(t, []string{"*.md"}, map[string]string{
+		"posts/chart.md": "---\ntitle: Chart\ndate: 2024-01-01\n---\n```mermaid\ngraph TD; A-->B\n```\n",
+	})
+	for _, tt := range []struct {
+		theme string
+		want  string
+	}{
+		{themeLight, `theme: "default"`},
+		{themeDark, `theme: "dark"`},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/post/chart", nil)
+		req.AddCookie(&http.Cookie{Name: themeCookie, Value: tt.theme})
+		rec := httptest.NewRecorder()
+		PostHandler(rec, req)
+		if !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("with the %s theme, expected %q in the page, got:\n%s", tt.theme, tt.want, rec.Body.String())
+		}
+	}
+}
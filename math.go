@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// The math extension recognizes $...$ inline math and $$...$$ display math
+// and renders them wrapped in \(...\) and \[...\] delimiters inside elements
+// with the "math" class, ready for KaTeX's auto-render script. Code spans and
+// code blocks are parsed before it, so dollar signs inside them are left alone.
+
+// KindMathInline is the node kind of inline math.
+var KindMathInline = ast.NewNodeKind("MathInline")
+
+// KindMathBlock is the node kind of a display math block.
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+// MathInline is a $...$ or $$...$$ expression within a paragraph.
+type MathInline struct {
+	ast.BaseInline
+	Display bool
+	Value   []byte
+}
+
+func (n *MathInline) Kind() ast.NodeKind { return KindMathInline }
+
+func (n *MathInline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Value": string(n.Value)}, nil)
+}
+
+// MathBlock is a display math block delimited by lines starting with $$.
+type MathBlock struct {
+	ast.BaseBlock
+	closed bool
+}
+
+func (n *MathBlock) Kind() ast.NodeKind { return KindMathBlock }
+
+func (n *MathBlock) IsRaw() bool { return true }
+
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+type mathInlineParser struct{}
+
+func (p *mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	delim := 1
+	if len(line) > 1 && line[1] == '$' {
+		delim = 2
+	}
+	rest := line[delim:]
+	// "$ 5" is a price, not math
+	if len(rest) == 0 || util.IsSpace(rest[0]) {
+		return nil
+	}
+
+	for i := 1; i < len(rest); i++ {
+		switch {
+		case rest[i] == '`':
+			// Leave code spans to the code span parser
+			return nil
+		case rest[i] == '\\':
+			i++
+		case rest[i] != '$':
+		case delim == 2 && (i+1 >= len(rest) || rest[i+1] != '$'):
+		case delim == 1 && (util.IsSpace(rest[i-1]) || (i+1 < len(rest) && isDigit(rest[i+1]))):
+			// A closing $ must follow a non-space and not precede a digit,
+			// so "$5 and $10" stays text
+		default:
+			node := &MathInline{Display: delim == 2, Value: append([]byte(nil), rest[:i]...)}
+			block.Advance(delim + i + delim)
+			return node
+		}
+	}
+	return nil
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+type mathBlockParser struct{}
+
+func (b *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (b *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+
+	node := &MathBlock{}
+	start := pos + 2
+	stop := len(line) - util.TrimRightSpaceLength(line)
+	if stop > start {
+		content := line[start:stop]
+		switch {
+		case bytes.HasSuffix(content, []byte("$$")):
+			// $$ x = 1 $$ on a single line
+			stop -= 2
+			node.closed = true
+		case bytes.Contains(content, []byte("$$")):
+			// $$x$$ followed by text is inline math in a paragraph
+			return nil, parser.NoChildren
+		}
+		node.Lines().Append(text.NewSegment(segment.Start+start, segment.Start+stop))
+	}
+	advanceToEOL(reader, line, segment)
+	return node, parser.NoChildren
+}
+
+func (b *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	math := node.(*MathBlock)
+	if math.closed {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	trimmed := util.TrimRightSpace(util.TrimLeftSpace(line))
+	if bytes.HasSuffix(trimmed, []byte("$$")) {
+		if len(trimmed) > 2 {
+			start := bytes.Index(line, trimmed)
+			math.Lines().Append(text.NewSegment(segment.Start+start, segment.Start+start+len(trimmed)-2))
+		}
+		advanceToEOL(reader, line, segment)
+		return parser.Close
+	}
+
+	math.Lines().Append(segment)
+	advanceToEOL(reader, line, segment)
+	return parser.Continue | parser.NoChildren
+}
+
+// advanceToEOL moves the reader to the end of the current line, leaving the
+// newline for the block parser loop to consume.
+func advanceToEOL(reader text.Reader, line []byte, segment text.Segment) {
+	newline := 0
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		newline = 1
+	}
+	reader.Advance(segment.Stop - segment.Start - newline + segment.Padding)
+}
+
+func (b *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (b *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// mathClass starts the class of rendered math; post pages load KaTeX only
+// when the content contains it.
+const mathClass = `class="math `
+
+type mathRenderer struct{}
+
+func (r *mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMathInline, r.renderInline)
+	reg.Register(KindMathBlock, r.renderBlock)
+}
+
+func (r *mathRenderer) renderInline(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	node := n.(*MathInline)
+	if node.Display {
+		w.WriteString(`<span class="math display">\[`)
+		w.Write(util.EscapeHTML(node.Value))
+		w.WriteString(`\]</span>`)
+	} else {
+		w.WriteString(`<span class="math inline">\(`)
+		w.Write(util.EscapeHTML(node.Value))
+		w.WriteString(`\)</span>`)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *mathRenderer) renderBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	w.WriteString(`<div class="math display">\[`)
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		w.Write(util.EscapeHTML(segment.Value(source)))
+	}
+	w.WriteString("\\]</div>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+type mathExtension struct{}
+
+// mathExt adds $...$ and $$...$$ math support to a goldmark converter.
+var mathExt = &mathExtension{}
+
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 150)),
+		parser.WithInlineParsers(util.Prioritized(&mathInlineParser{}, 150)),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(util.Prioritized(&mathRenderer{}, 500)),
+	)
+}
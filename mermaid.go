@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// The mermaid extension turns ```mermaid code blocks into
+// <div class="mermaid"> elements holding the raw diagram source, which the
+// Mermaid script renders in the browser. Other code blocks are left to the
+// highlighter.
+
+// KindMermaidBlock is the node kind of a Mermaid diagram.
+var KindMermaidBlock = ast.NewNodeKind("MermaidBlock")
+
+// MermaidBlock is a fenced code block in the mermaid language.
+type MermaidBlock struct {
+	ast.BaseBlock
+}
+
+func (n *MermaidBlock) Kind() ast.NodeKind { return KindMermaidBlock }
+
+func (n *MermaidBlock) IsRaw() bool { return true }
+
+func (n *MermaidBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// mermaidClass marks rendered diagrams; post pages load the Mermaid script
+// only when their content contains it.
+const mermaidClass = `<div class="mermaid">`
+
+type mermaidTransformer struct{}
+
+func (t *mermaidTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if block, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			if bytes.Equal(block.Language(reader.Source()), []byte("mermaid")) {
+				blocks = append(blocks, block)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// Replace after walking so the walk does not see a modified tree
+	for _, block := range blocks {
+		diagram := &MermaidBlock{}
+		diagram.SetLines(block.Lines())
+		block.Parent().ReplaceChild(block.Parent(), block, diagram)
+	}
+}
+
+type mermaidRenderer struct{}
+
+func (r *mermaidRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMermaidBlock, r.renderBlock)
+}
+
+func (r *mermaidRenderer) renderBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	w.WriteString(mermaidClass + "\n")
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		w.Write(util.EscapeHTML(segment.Value(source)))
+	}
+	w.WriteString("</div>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+type mermaidExtension struct{}
+
+// mermaidExt renders ```mermaid code blocks as Mermaid diagrams.
+var mermaidExt = &mermaidExtension{}
+
+func (e *mermaidExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(&mermaidTransformer{}, 500)),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(util.Prioritized(&mermaidRenderer{}, 500)),
+	)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statusRecorder wraps a ResponseWriter to capture the status code and the
+// number of bytes written.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (s *statusRecorder) WriteHeader(statusCode int) {
+	s.status = statusCode
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.size += n
+	return n, err
+}
+
+// LogRequests logs the method, path, status, response size, and duration of
+// every request handled by next.
+func LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		logAccess(r, rec.status, rec.size, time.Since(start))
+	})
+}
+
+// defaultCSP allows the inline styles used by the templates and the syntax
+// highlighter, the inline event handlers, and the KaTeX, Mermaid, and Font
+// Awesome files loaded from their CDNs.
+const defaultCSP = "default-src 'self'; " +
+	"img-src 'self' https: data:; " +
+	"style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net https://cdnjs.cloudflare.com; " +
+	"font-src 'self' data: https://cdn.jsdelivr.net https://cdnjs.cloudflare.com; " +
+	"script-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net; " +
+	"frame-ancestors 'self'"
+
+// contentSecurityPolicy is sent with every response. It is read once from
+// BLOG_CSP at startup and defaults to defaultCSP.
+var contentSecurityPolicy = resolveCSP(os.Getenv("BLOG_CSP"))
+
+// resolveCSP returns policy, or defaultCSP when it is empty.
+func resolveCSP(policy string) string {
+	if policy == "" {
+		return defaultCSP
+	}
+	return policy
+}
+
+// SecurityHeaders sets headers that restrict content sniffing, framing, the
+// referrer sent to other sites, and where pages may load resources from.
+func SecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "SAMEORIGIN")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		h.Set("Content-Security-Policy", contentSecurityPolicy)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// gzipMinSize is the smallest response body worth compressing.
+const gzipMinSize = 1024
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the body is large enough and of a type worth compressing.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(statusCode int) {
+	if g.status == 0 {
+		g.status = statusCode
+	}
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(b)
+		}
+		return g.ResponseWriter.Write(b)
+	}
+
+	g.buf = append(g.buf, b...)
+	if len(g.buf) >= gzipMinSize {
+		if err := g.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// decide sends the headers and the buffered bytes, compressing them when the
+// body is big enough and not already compressed.
+func (g *gzipResponseWriter) decide(large bool) error {
+	g.decided = true
+	h := g.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(g.buf))
+	}
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+
+	// Partial content refers to byte ranges of the uncompressed body
+	if large && g.status != http.StatusPartialContent && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.ResponseWriter.WriteHeader(g.status)
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+		_, err := g.gz.Write(g.buf)
+		return err
+	}
+
+	g.ResponseWriter.WriteHeader(g.status)
+	_, err := g.ResponseWriter.Write(g.buf)
+	return err
+}
+
+// Close flushes a response that stayed below gzipMinSize and finishes the
+// gzip stream otherwise.
+func (g *gzipResponseWriter) Close() error {
+	if !g.decided {
+		if len(g.buf) == 0 && g.status != 0 {
+			g.ResponseWriter.WriteHeader(g.status)
+			return nil
+		}
+		if len(g.buf) == 0 {
+			return nil
+		}
+		return g.decide(false)
+	}
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	return nil
+}
+
+// compressible reports whether a response of the given content type benefits
+// from gzip. Images, archives, audio, video, and fonts are usually already
+// compressed.
+func compressible(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch {
+	case mediaType == "image/svg+xml":
+		return true
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "font/"):
+		return false
+	}
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip",
+		"application/x-bzip2", "application/x-7z-compressed", "application/pdf",
+		"application/octet-stream":
+		return false
+	}
+	return true
+}
+
+// Gzip compresses responses for clients that accept gzip encoding.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		// gzip;q=0 explicitly refuses the encoding
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			weight, err := strconv.ParseFloat(q, 64)
+			return err == nil && weight > 0
+		}
+		return true
+	}
+	return false
+}
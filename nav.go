@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/fs"
+)
+
+// Markdown files behind the optional About and Contact pages.
+const (
+	aboutFile   = "nav/about.md"
+	contactFile = "nav/contact.md"
+)
+
+// NavPages records which optional nav pages have a Markdown file, so routes
+// and nav links are only added for pages that exist.
+type NavPages struct {
+	HasAbout   bool
+	HasContact bool
+}
+
+// navPages is detected once at startup.
+var navPages = detectNavPages()
+
+// detectNavPages checks which nav page files exist in the content directory.
+func detectNavPages() NavPages {
+	return NavPages{
+		HasAbout:   fileExists(aboutFile),
+		HasContact: fileExists(contactFile),
+	}
+}
+
+// fileExists reports whether name is a regular file in the content directory.
+func fileExists(name string) bool {
+	info, err := fs.Stat(contentFS(), name)
+	return err == nil && !info.IsDir()
+}
+
+// MenuItem is a link in the site's nav menu.
+type MenuItem struct {
+	Label string `yaml:"label"`
+	Href  string `yaml:"href"`
+}
+
+// menuItems returns the nav menu: the menu list in the config file, or when it
+// has none, the built-in pages with About and Contact only if they exist, and
+// Search except in static builds, which have no search handler.
+func menuItems() []MenuItem {
+	if len(config.Menu) > 0 {
+		return config.Menu
+	}
+	menu := []MenuItem{
+		{Label: "Home", Href: "/"},
+		{Label: "Archive", Href: "/archive"},
+		{Label: "Notes", Href: "/notes"},
+	}
+	if navPages.HasAbout {
+		menu = append(menu, MenuItem{Label: "About", Href: "/about"})
+	}
+	if navPages.HasContact {
+		menu = append(menu, MenuItem{Label: "Contact", Href: "/contact"})
+	}
+	if staticBuild {
+		return menu
+	}
+	return append(menu, MenuItem{Label: "Search", Href: "/search"})
+}
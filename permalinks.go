@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// The URL schemes blog posts can be served under, set by the permalinks key
+// of the config file.
+const (
+	slugPermalinks  = "slug"
+	datedPermalinks = "dated"
+)
+
+// datedPath matches the /YYYY/MM/<slug> URLs of dated permalinks.
+var datedPath = regexp.MustCompile(`^/(\d{4})/(\d{2})/(.+)$`)
+
+// usesDatedPermalinks reports whether blog posts are served under
+// /YYYY/MM/<slug>. Notes always keep their /note/<slug> URLs.
+func usesDatedPermalinks() bool {
+	return config.Permalinks == datedPermalinks
+}
+
+// DatedPostHandler serves a blog post at /YYYY/MM/<slug>. The year and month
+// must match the post's date. Like /post/<slug>, it serves the Markdown source
+// when the slug ends in .md.
+func DatedPostHandler(w http.ResponseWriter, r *http.Request) {
+	m := datedPath.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		NotFoundHandler(w, r)
+		return
+	}
+	slug, source := sourceSlug(m[3], r)
+	post, err := LoadSectionPost(postsSection, slug)
+	if err != nil {
+		postLoadError(w, r, err)
+		return
+	}
+	year, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	if post.Date.Year() != year || int(post.Date.Month()) != month {
+		NotFoundHandler(w, r)
+		return
+	}
+	if source {
+		servePostSource(w, post)
+		return
+	}
+	renderPost(w, r, postsSection, post)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// previewToken is the secret that unlocks draft previews. It is read once
+// from BLOG_PREVIEW_TOKEN at startup; drafts cannot be previewed when it is
+// unset.
+var previewToken = os.Getenv("BLOG_PREVIEW_TOKEN")
+
+// validPreviewToken reports whether token matches previewToken, comparing in
+// constant time so the secret cannot be guessed from response times.
+func validPreviewToken(token string) bool {
+	if previewToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(previewToken)) == 1
+}
+
+// PreviewHandler serves /preview/<slug>, a shareable link to a post. Drafts
+// and scheduled posts are only shown with ?token= set to the preview token;
+// published posts are shown to anyone.
+func PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	slug := strings.TrimPrefix(r.URL.Path, "/preview/")
+	post, err := findSectionPost(postsSection, slug)
+	if err != nil {
+		postLoadError(w, r, err)
+		return
+	}
+	if post.Draft || post.Scheduled() {
+		if !validPreviewToken(r.URL.Query().Get("token")) {
+			NotFoundHandler(w, r)
+			return
+		}
+		// Keep shared drafts and scheduled posts out of search engines and shared caches
+		w.Header().Set("X-Robots-Tag", "noindex")
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+	renderPost(w, r, postsSection, post)
+}
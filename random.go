@@ -0,0 +1,26 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+)
+
+// RandomHandler redirects to a randomly chosen published post. The top-level
+// math/rand functions are seeded randomly at startup, so the choice differs
+// between runs.
+func RandomHandler(w http.ResponseWriter, r *http.Request) {
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+	if len(posts) == 0 {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	post := posts[rand.Intn(len(posts))]
+	// Every request should pick again rather than reuse a cached redirect
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, post.Path(), http.StatusFound)
+}
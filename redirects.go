@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultRedirectsFile is the redirects map read when -redirects is not set.
+const defaultRedirectsFile = "redirects.yaml"
+
+// Redirect is the target of an entry in the redirects map. An entry is either
+// a plain path, redirected with 301 Moved Permanently, or a mapping with "to"
+// and an optional "status" such as 302 for temporary moves.
+type Redirect struct {
+	To     string `yaml:"to"`
+	Status int    `yaml:"status"`
+}
+
+// UnmarshalYAML accepts both the plain path and the mapping form.
+func (rd *Redirect) UnmarshalYAML(unmarshal func(any) error) error {
+	var to string
+	if err := unmarshal(&to); err == nil {
+		*rd = Redirect{To: to, Status: http.StatusMovedPermanently}
+		return nil
+	}
+	type plain Redirect
+	var entry plain
+	if err := unmarshal(&entry); err != nil {
+		return err
+	}
+	if entry.Status == 0 {
+		entry.Status = http.StatusMovedPermanently
+	}
+	*rd = Redirect(entry)
+	return nil
+}
+
+// redirectStatuses are the status codes a redirect may use. Other 3xx codes
+// such as 300 Multiple Choices or 304 Not Modified do not redirect browsers.
+var redirectStatuses = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusSeeOther:          true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// redirects maps old request paths to their new location. It is empty until
+// main replaces it with the result of LoadRedirects.
+var redirects map[string]Redirect
+
+// LoadRedirects reads the YAML redirects map at path. A missing file is not an
+// error and yields no redirects.
+func LoadRedirects(path string) (map[string]Redirect, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]Redirect
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for from, rd := range m {
+		if rd.To == "" {
+			return nil, fmt.Errorf("redirect for %s has no target", from)
+		}
+		if !redirectStatuses[rd.Status] {
+			return nil, fmt.Errorf("redirect for %s has status %d, want 301, 302, 303, 307 or 308", from, rd.Status)
+		}
+	}
+	return m, nil
+}
+
+// Redirects answers requests for paths in the redirects map before they reach
+// next, so renamed posts keep their old links working.
+func Redirects(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rd, ok := redirects[r.URL.Path]; ok {
+			http.Redirect(w, r, rd.To, rd.Status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import "sort"
+
+// relatedLimit is the maximum number of related posts shown under a post.
+const relatedLimit = 5
+
+// relatedPosts returns up to relatedLimit posts that share the most tags
+// with post, excluding post itself. Ties are broken by recency, whatever the
+// order of posts. A post without tags gets the most recent posts.
+func relatedPosts(post PostData, posts []PostData) []PostData {
+	type scored struct {
+		post   PostData
+		shared int
+	}
+
+	var candidates []scored
+	for _, other := range newestFirst(posts) {
+		if other.Slug == post.Slug {
+			continue
+		}
+		shared := 0
+		for _, tag := range post.Tags {
+			if hasTag(other, tag) {
+				shared++
+			}
+		}
+		if len(post.Tags) > 0 && shared == 0 {
+			continue
+		}
+		candidates = append(candidates, scored{post: other, shared: shared})
+	}
+
+	// The stable sort keeps the newest-first order among equal scores
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].shared > candidates[j].shared
+	})
+
+	var related []PostData
+	for _, c := range candidates {
+		if len(related) == relatedLimit {
+			break
+		}
+		related = append(related, c.post)
+	}
+	return related
+}
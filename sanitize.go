@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// sanitizeHTML enables the sanitization pass over rendered Markdown. It is
+// read once from BLOG_SANITIZE at startup. When off, raw HTML in Markdown is
+// dropped by goldmark; when on, it is rendered and then cleaned by
+// contentPolicy, so harmless markup such as <details> or <kbd> survives while
+// scripts and event handlers do not.
+var sanitizeHTML = resolveSanitize(os.Getenv("BLOG_SANITIZE"))
+
+// resolveSanitize parses value as a boolean, treating empty or invalid values
+// as false.
+func resolveSanitize(value string) bool {
+	if value == "" {
+		return false
+	}
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		logWarn("Invalid BLOG_SANITIZE %q, not sanitizing", value)
+		return false
+	}
+	return on
+}
+
+// contentPolicy allows the user-generated-content subset of HTML plus the
+// markup the Markdown extensions produce: highlighting and math classes,
+// heading anchors, footnote roles, lazy images, task list checkboxes,
+// focusable code blocks, and external links opening in a new tab.
+var contentPolicy = newContentPolicy()
+
+func newContentPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(false)
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w\- ]+$`)).Globally()
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\w\-:.]+$`)).Globally()
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-[a-z]+$`)).Globally()
+	p.AllowAttrs("aria-label").Globally()
+	p.AllowAttrs("rel").OnElements("a")
+	p.AllowAttrs("target").Matching(regexp.MustCompile(`^_blank$`)).OnElements("a")
+	p.AllowAttrs("loading").Matching(regexp.MustCompile(`^lazy$`)).OnElements("img")
+	p.AllowAttrs("decoding").Matching(regexp.MustCompile(`^async$`)).OnElements("img")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowAttrs("tabindex").Matching(regexp.MustCompile(`^0$`)).OnElements("pre")
+	p.AllowElements("figure", "figcaption", "details", "summary", "kbd")
+	return p
+}
+
+// sanitize returns html cleaned by contentPolicy when sanitizeHTML is on, and
+// html unchanged otherwise.
+func sanitize(html []byte) []byte {
+	if !sanitizeHTML {
+		return html
+	}
+	return contentPolicy.SanitizeBytes(html)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// snippetRadius is the number of bytes of context shown on each side of the
+// first match in a search result.
+const snippetRadius = 80
+
+// SearchResult is a post matching a search query.
+type SearchResult struct {
+	Post    PostData
+	Matches int
+	Snippet template.HTML
+}
+
+// searchPosts returns the posts whose title or Markdown source contain the
+// query, ignoring case, ranked by the number of matches. Posts with the same
+// number of matches keep their date order.
+func searchPosts(posts []PostData, query string) []SearchResult {
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+
+	var results []SearchResult
+	for _, post := range posts {
+		body := string(post.source)
+		bodyMatches := re.FindAllStringIndex(body, -1)
+		matches := len(re.FindAllStringIndex(post.Title, -1)) + len(bodyMatches)
+		if matches == 0 {
+			continue
+		}
+
+		result := SearchResult{Post: post, Matches: matches}
+		if len(bodyMatches) > 0 {
+			result.Snippet = snippet(body, bodyMatches[0][0], bodyMatches[0][1])
+		}
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Matches > results[j].Matches
+	})
+	return results
+}
+
+// snippet returns the text around body[start:end] with the match wrapped in
+// a <mark> element. Everything else is HTML-escaped.
+func snippet(body string, start, end int) template.HTML {
+	from := start - snippetRadius
+	if from < 0 {
+		from = 0
+	}
+	to := end + snippetRadius
+	if to > len(body) {
+		to = len(body)
+	}
+	// Don't cut a multi-byte character in half
+	for from > 0 && !isRuneStart(body[from]) {
+		from--
+	}
+	for to < len(body) && !isRuneStart(body[to]) {
+		to++
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	b.WriteString(html.EscapeString(collapseSpace(body[from:start])))
+	b.WriteString("<mark>")
+	b.WriteString(html.EscapeString(body[start:end]))
+	b.WriteString("</mark>")
+	b.WriteString(html.EscapeString(collapseSpace(body[end:to])))
+	if to < len(body) {
+		b.WriteString("…")
+	}
+	return template.HTML(b.String())
+}
+
+// isRuneStart reports whether b is the first byte of a UTF-8 encoded rune.
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// whitespace matches runs of whitespace, including newlines.
+var whitespace = regexp.MustCompile(`\s+`)
+
+// collapseSpace replaces runs of whitespace with a single space.
+func collapseSpace(s string) string {
+	return whitespace.ReplaceAllString(s, " ")
+}
+
+// SearchHandler renders the search page and, when a query is given, the
+// matching posts.
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	var results []SearchResult
+	if query != "" {
+		posts, err := LoadBlogPosts()
+		if err != nil {
+			InternalErrorHandler(w, r, err)
+			return
+		}
+		results = searchPosts(posts, query)
+	}
+
+	data := struct {
+		Title     string
+		Canonical string
+		Theme     string
+		Query     string
+		Results   []SearchResult
+	}{
+		Title:     "Search",
+		Canonical: canonicalURL(r),
+		Theme:     themeFor(r),
+		Query:     query,
+		Results:   results,
+	}
+
+	if err := renderTemplate(w, "search", data); err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Section is a content directory whose posts are served under their own URL
+// prefix, so slugs only need to be unique within a section.
+type Section struct {
+	Name string // URL path segment, e.g. "post" for /post/<slug>
+	Dir  string // Directory holding the section's Markdown files
+}
+
+var (
+	postsSection = Section{Name: "post", Dir: "posts"}
+	notesSection = Section{Name: "note", Dir: "notes"}
+)
+
+// Prefix returns the URL path prefix of the section's posts.
+func (s Section) Prefix() string {
+	return "/" + s.Name + "/"
+}
+
+// Path returns the URL path of the post, with a trailing slash when
+// trailing_slash is "add". Nested slugs keep their slashes.
+func (p PostData) Path() string {
+	return slashPath(p.escapedPath())
+}
+
+// SourcePath returns the URL path of the post's Markdown source.
+func (p PostData) SourcePath() string {
+	return p.escapedPath() + ".md"
+}
+
+// escapedPath returns rawPath with every segment escaped.
+func (p PostData) escapedPath() string {
+	segments := strings.Split(p.rawPath(), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// rawPath returns the unescaped URL path of the post, which is also where the
+// static site generator writes it. Blog posts use /YYYY/MM/<slug> with dated
+// permalinks.
+func (p PostData) rawPath() string {
+	if p.Section == postsSection.Name && usesDatedPermalinks() {
+		return p.Date.Format("/2006/01/") + p.Slug
+	}
+	return "/" + p.Section + "/" + p.Slug
+}
+
+// warnedSlugs remembers which duplicate slugs have been reported so the
+// warning is logged once rather than on every request.
+var warnedSlugs sync.Map
+
+// sectionFiles returns the Markdown files under the section directory,
+// including those in subdirectories, as selected by isPostFile. A missing
+// directory has no files.
+func sectionFiles(section Section) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(contentFS(), section.Dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if file == section.Dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() && isPostFile(file) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// warnedFiles remembers which unloadable files have been reported so the
+// error is logged once rather than on every request.
+var warnedFiles sync.Map
+
+// loadAllPosts loads every post of a section, drafts included. Posts in
+// subdirectories get their directory as a slug prefix, e.g. posts/go/channels.md
+// becomes go/channels, unless the frontmatter sets a slug. When two files
+// share a slug the first one wins and a warning is logged. Files that fail to
+// load are logged and skipped, so one broken post does not take down the
+// others; findSectionPost still reports the error for the post itself.
+func loadAllPosts(section Section) ([]PostData, error) {
+	files, err := sectionFiles(section)
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []PostData
+	slugs := make(map[string]string, len(files))
+	for _, file := range files {
+		post, err := loadSectionFile(section, file)
+		if err != nil {
+			if _, warned := warnedFiles.LoadOrStore(err.Error(), true); !warned {
+				logError("Skipping %s: %v", file, err)
+			}
+			continue
+		}
+		if other, ok := slugs[post.Slug]; ok {
+			if _, warned := warnedSlugs.LoadOrStore(file, true); !warned {
+				logWarn("Slug %q of %s is already used by %s, skipping it", post.Slug, file, other)
+			}
+			continue
+		}
+		slugs[post.Slug] = file
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// loadSectionFile loads a post file of the section and sets its section and
+// its final slug.
+func loadSectionFile(section Section, file string) (PostData, error) {
+	post, err := postCache.Load(file)
+	if err != nil {
+		return PostData{}, err
+	}
+	post.Section = section.Name
+	if dir := path.Dir(strings.TrimPrefix(file, section.Dir+"/")); dir != "." && post.slugFromName {
+		post.Slug = dir + "/" + post.Slug
+	}
+	return post, nil
+}
+
+// SlugConflict lists the files of a section whose slugs collide. Slugs that
+// differ only in case collide too, since they map to the same directory in a
+// static build on case-insensitive file systems.
+type SlugConflict struct {
+	Section string
+	Slug    string
+	Files   []string
+}
+
+// findSlugConflicts returns every slug shared by more than one file across the
+// sections, drafts included, in file order.
+func findSlugConflicts(sections ...Section) ([]SlugConflict, error) {
+	var conflicts []SlugConflict
+	for _, section := range sections {
+		files, err := sectionFiles(section)
+		if err != nil {
+			return nil, err
+		}
+		index := make(map[string]int)
+		var found []SlugConflict
+		for _, file := range files {
+			post, err := loadSectionFile(section, file)
+			if err != nil {
+				return nil, err
+			}
+			key := strings.ToLower(post.Slug)
+			if i, ok := index[key]; ok {
+				found[i].Files = append(found[i].Files, file)
+				continue
+			}
+			index[key] = len(found)
+			found = append(found, SlugConflict{Section: section.Name, Slug: post.Slug, Files: []string{file}})
+		}
+		for _, c := range found {
+			if len(c.Files) > 1 {
+				conflicts = append(conflicts, c)
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// checkSlugs logs an error for every slug collision and reports whether the
+// content is free of them.
+func checkSlugs() (bool, error) {
+	conflicts, err := findSlugConflicts(postsSection, notesSection)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range conflicts {
+		logError("Slug %q in %s is used by %s", c.Slug, c.Section, strings.Join(c.Files, ", "))
+	}
+	return len(conflicts) == 0, nil
+}
+
+// now returns the current time. Tests replace it to move the clock past the
+// date of scheduled posts.
+var now = time.Now
+
+// Scheduled reports whether the post is dated in the future. It stays out of
+// listings and feeds until its date arrives.
+func (p PostData) Scheduled() bool {
+	return p.Date.After(now().In(displayLocation))
+}
+
+// nextScheduled returns the date of the earliest scheduled post of the
+// section that is not a draft, or the zero time when none is pending. Caches
+// of published posts are stale once it has passed.
+func nextScheduled(section Section) (time.Time, error) {
+	all, err := loadAllPosts(section)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var next time.Time
+	for _, post := range all {
+		if !post.Draft && post.Scheduled() && (next.IsZero() || post.Date.Before(next)) {
+			next = post.Date
+		}
+	}
+	return next, nil
+}
+
+// LoadSectionPosts loads the published posts of a section and sorts them with
+// sortPosts. Drafts and scheduled posts are skipped.
+func LoadSectionPosts(section Section) ([]PostData, error) {
+	all, err := loadAllPosts(section)
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []PostData
+	for _, post := range all {
+		if post.Draft || post.Scheduled() {
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	sortPosts(posts)
+	return posts, nil
+}
+
+// validSlug matches the characters allowed in a post slug: one or more
+// slash-separated segments, none of which can be "." or "..".
+var validSlug = regexp.MustCompile(`^[A-Za-z0-9_-]+(/[A-Za-z0-9_-]+)*$`)
+
+// LoadSectionPost loads a single post of a section by slug. Drafts and
+// scheduled posts are only returned when showDrafts is enabled.
+func LoadSectionPost(section Section, slug string) (PostData, error) {
+	post, err := findSectionPost(section, slug)
+	if err != nil {
+		return PostData{}, err
+	}
+	if (post.Draft || post.Scheduled()) && !showDrafts() {
+		return PostData{}, os.ErrNotExist
+	}
+	return post, nil
+}
+
+// findSectionPost loads a single post of a section by slug, drafts included.
+func findSectionPost(section Section, slug string) (PostData, error) {
+	// Reject anything that could point outside the section directory. Slugs
+	// are only matched against the files found under it, never opened as paths.
+	if !validSlug.MatchString(slug) {
+		return PostData{}, os.ErrNotExist
+	}
+
+	// Find the Markdown file with the given slug, which may come from the
+	// frontmatter rather than the filename
+	all, err := loadAllPosts(section)
+	if err != nil {
+		return PostData{}, err
+	}
+	for _, post := range all {
+		if post.Slug == slug {
+			return post, nil
+		}
+	}
+
+	// A file skipped for failing to load answers for the slug of its name
+	files, err := sectionFiles(section)
+	if err != nil {
+		return PostData{}, err
+	}
+	for _, file := range files {
+		if fileSlug(section, file) != slug {
+			continue
+		}
+		if _, err := loadSectionFile(section, file); err != nil {
+			return PostData{}, err
+		}
+	}
+	return PostData{}, os.ErrNotExist
+}
+
+// fileSlug returns the slug a section file gets from its name, e.g.
+// go/channels for posts/go/channels.md.
+func fileSlug(section Section, file string) string {
+	name := strings.TrimPrefix(file, section.Dir+"/")
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// NotesHandler lists the posts of the notes section.
+func NotesHandler(w http.ResponseWriter, r *http.Request) {
+	notes, err := LoadSectionPosts(notesSection)
+	if err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+
+	data := newListingData(r, "Notes", notes)
+
+	if err := renderTemplate(w, "notes", data); err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// SeriesInfo places a post within the series it belongs to.
+type SeriesInfo struct {
+	Name  string
+	Part  int        // 1-based position of the post in the series
+	Total int        // Number of posts in the series
+	Posts []PostData // Every post of the series, in reading order
+}
+
+// seriesPosts returns the posts of the named series, ignoring case, in reading
+// order. Posts with a part number come first, in ascending order; posts
+// without one follow, oldest first.
+func seriesPosts(posts []PostData, name string) []PostData {
+	var parts []PostData
+	for _, post := range posts {
+		if post.Series != "" && strings.EqualFold(post.Series, name) {
+			parts = append(parts, post)
+		}
+	}
+
+	sort.SliceStable(parts, func(i, j int) bool {
+		pi, pj := parts[i].Part, parts[j].Part
+		switch {
+		case pi != nil && pj == nil:
+			return true
+		case pi == nil && pj != nil:
+			return false
+		case pi != nil && pj != nil && *pi != *pj:
+			return *pi < *pj
+		}
+		return parts[i].Date.Before(parts[j].Date)
+	})
+	return parts
+}
+
+// seriesInfo returns the position of post within its series, or nil when the
+// post is not part of one or is not listed in posts, as with previewed drafts.
+func seriesInfo(post PostData, posts []PostData) *SeriesInfo {
+	if post.Series == "" {
+		return nil
+	}
+	parts := seriesPosts(posts, post.Series)
+	for i, p := range parts {
+		if p.Slug == post.Slug {
+			return &SeriesInfo{Name: post.Series, Part: i + 1, Total: len(parts), Posts: parts}
+		}
+	}
+	return nil
+}
+
+// seriesNames returns every distinct series across the posts sorted by name.
+// Names that differ only in case are treated as the same series.
+func seriesNames(posts []PostData) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, post := range posts {
+		key := strings.ToLower(post.Series)
+		if post.Series == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, post.Series)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names
+}
+
+// SeriesHandler renders the posts of the series named in the path in reading
+// order.
+func SeriesHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/series/")
+	if name == "" {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+
+	parts := seriesPosts(posts, name)
+	if len(parts) == 0 {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	data := TemplateData{
+		Title:     "Series: " + parts[0].Series,
+		Canonical: canonicalURL(r),
+		Theme:     themeFor(r),
+		Posts:     parts,
+	}
+
+	if err := renderTemplate(w, "series", data); err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"os"
+)
+
+// urlSet is the root element of a sitemap document.
+type urlSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+}
+
+// SitemapHandler serves a sitemap listing the site's pages and every post.
+func SitemapHandler(w http.ResponseWriter, r *http.Request) {
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		http.Error(w, "Error loading posts", http.StatusInternalServerError)
+		return
+	}
+	posts = newestFirst(posts)
+
+	base := BaseURL()
+	home := sitemapURL{Loc: base + "/", ChangeFreq: "daily"}
+	if len(posts) > 0 {
+		home.LastMod = lastUpdated(posts).Format("2006-01-02")
+	}
+	sitemap := urlSet{URLs: []sitemapURL{home}}
+	if navPages.HasAbout {
+		sitemap.URLs = append(sitemap.URLs, sitemapURL{Loc: base + "/about", ChangeFreq: "yearly"})
+	}
+	if navPages.HasContact {
+		sitemap.URLs = append(sitemap.URLs, sitemapURL{Loc: base + "/contact", ChangeFreq: "yearly"})
+	}
+	notes, err := LoadSectionPosts(notesSection)
+	if err != nil {
+		http.Error(w, "Error loading notes", http.StatusInternalServerError)
+		return
+	}
+	if len(notes) > 0 {
+		sitemap.URLs = append(sitemap.URLs, sitemapURL{Loc: base + "/notes", ChangeFreq: "weekly"})
+	}
+
+	for _, post := range append(posts, notes...) {
+		sitemap.URLs = append(sitemap.URLs, sitemapURL{
+			Loc:        base + post.Path(),
+			LastMod:    post.Updated.Format("2006-01-02"),
+			ChangeFreq: "monthly",
+		})
+	}
+
+	out, err := xml.MarshalIndent(sitemap, "", "  ")
+	if err != nil {
+		http.Error(w, "Error generating sitemap", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	w.Write(out)
+}
+
+// RobotsHandler serves robots.txt. The policy is read from the file named by
+// BLOG_ROBOTS_FILE; without one, every crawler is allowed and pointed at the
+// sitemap.
+func RobotsHandler(w http.ResponseWriter, r *http.Request) {
+	policy := "User-agent: *\nAllow: /\n\nSitemap: " + BaseURL() + "/sitemap.xml\n"
+	if file := os.Getenv("BLOG_ROBOTS_FILE"); file != "" {
+		custom, err := os.ReadFile(file)
+		if err != nil {
+			logError("Error reading robots policy %s: %v", file, err)
+			http.Error(w, "Error loading robots.txt", http.StatusInternalServerError)
+			return
+		}
+		policy = string(custom)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(policy))
+}
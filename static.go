@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+)
+
+// staticDir is the directory served under /static/.
+const staticDir = "static"
+
+// staticFileSystem wraps an http.FileSystem and refuses to open directories,
+// so http.FileServer answers 404 instead of rendering a directory listing.
+type staticFileSystem struct {
+	fs http.FileSystem
+}
+
+func (s staticFileSystem) Open(name string) (http.File, error) {
+	f, err := s.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return f, nil
+}
+
+// StaticHandler serves files from the static directory of siteFS. fs.FS
+// rejects paths that would escape the directory, and directory requests get
+// a 404.
+func StaticHandler() http.Handler {
+	static, err := fs.Sub(siteFS, staticDir)
+	if err != nil {
+		panic(err)
+	}
+	files := staticFileSystem{fs: http.FS(static)}
+	return http.StripPrefix("/static/", http.FileServer(files))
+}
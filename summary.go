@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultSummaryLength is the maximum number of characters in a generated
+// summary when BLOG_SUMMARY_LEN is unset.
+const defaultSummaryLength = 200
+
+// summaryLength is the maximum number of characters in a generated summary.
+// It is read once from BLOG_SUMMARY_LEN at startup.
+var summaryLength = resolveSummaryLength(os.Getenv("BLOG_SUMMARY_LEN"))
+
+// resolveSummaryLength parses value as a positive length, falling back to
+// defaultSummaryLength when it is empty or invalid.
+func resolveSummaryLength(value string) int {
+	if value == "" {
+		return defaultSummaryLength
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		logWarn("Invalid BLOG_SUMMARY_LEN %q, using %d", value, defaultSummaryLength)
+		return defaultSummaryLength
+	}
+	return n
+}
+
+// moreMarker separates the summary of a post from the rest of its body.
+const moreMarker = "<!--more-->"
+
+// splitMore removes the first moreMarker from a Markdown body. It returns the
+// body without the marker and the part before it, which is nil when the body
+// has no marker.
+func splitMore(body []byte) (rest, intro []byte) {
+	i := bytes.Index(body, []byte(moreMarker))
+	if i < 0 {
+		return body, nil
+	}
+	intro = body[:i:i]
+	rest = append(append([]byte(nil), intro...), body[i+len(moreMarker):]...)
+	return rest, intro
+}
+
+var (
+	// firstParagraph matches the first paragraph of rendered HTML.
+	firstParagraph = regexp.MustCompile(`(?s)<p>(.*?)</p>`)
+	// htmlTag matches a single HTML tag.
+	htmlTag = regexp.MustCompile(`<[^>]*>`)
+)
+
+// summarize returns the summary of a post. An explicit summary from the
+// frontmatter wins, then intro, the rendered part before the <!--more-->
+// marker; otherwise the first paragraph of the rendered content is used,
+// truncated to summaryLength characters.
+func summarize(matter FrontMatter, content, intro template.HTML) template.HTML {
+	if matter.Summary != "" {
+		return template.HTML(html.EscapeString(matter.Summary))
+	}
+	if intro != "" {
+		return intro
+	}
+
+	match := firstParagraph.FindStringSubmatch(string(content))
+	if match == nil {
+		return ""
+	}
+	paragraph := match[1]
+
+	// Tags are stripped before truncating so a cut can never land inside one
+	text := plainText(template.HTML(paragraph))
+	if utf8.RuneCountInString(text) <= summaryLength {
+		return template.HTML(paragraph)
+	}
+	return template.HTML(html.EscapeString(truncateWords(text, summaryLength)) + "…")
+}
+
+// plainText strips the tags from rendered HTML and unescapes its entities.
+func plainText(h template.HTML) string {
+	return strings.TrimSpace(html.UnescapeString(htmlTag.ReplaceAllString(string(h), "")))
+}
+
+// truncateWords shortens s to at most limit characters, cutting at the last
+// word boundary when there is one.
+func truncateWords(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	cut := string(runes[:limit])
+	if i := strings.LastIndexAny(cut, " \t\n"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " \t\n.,;:")
+}
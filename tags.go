@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// TagCount is a tag together with the number of posts that carry it.
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// hasTag reports whether the post carries the tag, ignoring case.
+func hasTag(post PostData, tag string) bool {
+	for _, t := range post.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// countTags returns every distinct tag across the posts sorted by name.
+// Tags that differ only in case are counted together.
+func countTags(posts []PostData) []TagCount {
+	index := make(map[string]int)
+	var tags []TagCount
+	for _, post := range posts {
+		for _, tag := range post.Tags {
+			key := strings.ToLower(tag)
+			if i, ok := index[key]; ok {
+				tags[i].Count++
+				continue
+			}
+			index[key] = len(tags)
+			tags = append(tags, TagCount{Name: tag, Count: 1})
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name)
+	})
+	return tags
+}
+
+// TagsHandler renders the list of all tags with their post counts.
+func TagsHandler(w http.ResponseWriter, r *http.Request) {
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+
+	data := struct {
+		Title     string
+		Canonical string
+		Theme     string
+		Tags      []TagCount
+	}{
+		Title:     "Tags",
+		Canonical: canonicalURL(r),
+		Theme:     themeFor(r),
+		Tags:      countTags(posts),
+	}
+
+	if err := renderTemplate(w, "tags", data); err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+}
+
+// TagHandler renders the posts carrying the tag named in the path.
+func TagHandler(w http.ResponseWriter, r *http.Request) {
+	tag := strings.TrimPrefix(r.URL.Path, "/tags/")
+	if tag == "" {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	posts, err := LoadBlogPosts()
+	if err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+
+	var tagged []PostData
+	for _, post := range posts {
+		if hasTag(post, tag) {
+			tagged = append(tagged, post)
+		}
+	}
+	if len(tagged) == 0 {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	data := newListingData(r, "Tagged: "+tag, tagged)
+
+	if err := renderTemplate(w, "tag", data); err != nil {
+		InternalErrorHandler(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// devMode parses templates on every request so edits show up without a
+// restart. It is enabled by setting BLOG_DEV=1.
+var devMode = os.Getenv("BLOG_DEV") == "1"
+
+var (
+	templatesMu     sync.RWMutex
+	templates       map[string]*template.Template
+	templatesErr    error
+	templatesLoaded bool
+)
+
+// templateFuncs are available to every template. site returns the active
+// Config, nav the optional nav pages that exist, menu the nav menu links,
+// footer the copyright line, favicon whether the favicon exists, and static
+// whether a static site is being generated, so layouts can use them without
+// each handler passing them.
+var templateFuncs = template.FuncMap{
+	"site":    func() Config { return config },
+	"nav":     func() NavPages { return navPages },
+	"menu":    menuItems,
+	"footer":  siteFooter,
+	"favicon": hasFavicon,
+	"static":  func() bool { return staticBuild },
+}
+
+// parseTemplate parses the named page template together with the base layout.
+func parseTemplate(name string) (*template.Template, error) {
+	return template.New("base.gohtml").Funcs(templateFuncs).ParseFS(siteFS, path.Join("templates", "base.gohtml"), path.Join("templates", name+".gohtml"))
+}
+
+// parseTemplates parses every page template in templates/ with the base
+// layout, keyed by the file name without extension.
+func parseTemplates() (map[string]*template.Template, error) {
+	files, err := fs.Glob(siteFS, path.Join("templates", "*.gohtml"))
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := make(map[string]*template.Template, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		if name == "base" {
+			continue
+		}
+		tmpl, err := parseTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		parsed[name] = tmpl
+	}
+	return parsed, nil
+}
+
+// LoadTemplates parses all templates once and caches them. It is called at
+// startup so template errors surface before the server accepts requests.
+func LoadTemplates() error {
+	templatesMu.RLock()
+	loaded, err := templatesLoaded, templatesErr
+	templatesMu.RUnlock()
+	if loaded {
+		return err
+	}
+
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	if !templatesLoaded {
+		templates, templatesErr = parseTemplates()
+		templatesLoaded = true
+	}
+	return templatesErr
+}
+
+// ReloadTemplates parses all templates again and replaces the cached ones.
+// When parsing fails the previous templates stay in use.
+func ReloadTemplates() error {
+	parsed, err := parseTemplates()
+	if err != nil {
+		return err
+	}
+	templatesMu.Lock()
+	templates, templatesErr, templatesLoaded = parsed, nil, true
+	templatesMu.Unlock()
+	return nil
+}
+
+// lookupTemplate returns the named page template, parsed fresh in dev mode
+// and from the cache otherwise.
+func lookupTemplate(name string) (*template.Template, error) {
+	if devMode {
+		return parseTemplate(name)
+	}
+	if err := LoadTemplates(); err != nil {
+		return nil, err
+	}
+	templatesMu.RLock()
+	tmpl, ok := templates[name]
+	templatesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("template %q not found", name)
+	}
+	return tmpl, nil
+}
+
+// standaloneTemplates are complete pages that do not use the base layout.
+var standaloneTemplates = map[string]bool{printLayout: true}
+
+// renderTemplate executes the named page template with the base layout, or
+// on its own for standaloneTemplates. The page is rendered into a buffer
+// first, so nothing is written to w when execution fails and the caller can
+// still send an error page.
+func renderTemplate(w io.Writer, name string, data any) error {
+	tmpl, err := lookupTemplate(name)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if standaloneTemplates[name] {
+		err = tmpl.ExecuteTemplate(&buf, name+".gohtml", data)
+	} else {
+		err = tmpl.Execute(&buf, data)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// themeCookie holds the color theme chosen by the reader.
+const themeCookie = "theme"
+
+// The color themes a reader can choose. With themeAuto, the default, the
+// browser's prefers-color-scheme setting decides.
+const (
+	themeAuto  = "auto"
+	themeLight = "light"
+	themeDark  = "dark"
+)
+
+// themeMaxAge is how long the theme cookie is kept, one year.
+const themeMaxAge = 365 * 24 * 60 * 60
+
+// themeFor returns the theme chosen by the reader of r, or themeAuto when the
+// cookie is missing or holds an unknown theme. Pages put it on <html> as a
+// theme-<name> class.
+func themeFor(r *http.Request) string {
+	cookie, err := r.Cookie(themeCookie)
+	if err != nil {
+		return themeAuto
+	}
+	switch cookie.Value {
+	case themeLight, themeDark:
+		return cookie.Value
+	}
+	return themeAuto
+}
+
+// ThemeHandler serves POST /theme, which stores the theme from the form value
+// "theme" in a cookie and redirects back to the page the form was sent from.
+// Choosing auto removes the cookie.
+func ThemeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     themeCookie,
+		Path:     "/",
+		MaxAge:   themeMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	switch theme := r.FormValue("theme"); theme {
+	case themeLight, themeDark:
+		cookie.Value = theme
+	case themeAuto:
+		cookie.MaxAge = -1
+	default:
+		http.Error(w, "Unknown theme", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, cookie)
+	http.Redirect(w, r, themeReturnPath(r), http.StatusSeeOther)
+}
+
+// themeReturnPath returns the path of the page that sent the theme form,
+// taken from the Referer header. Only the path and query are kept, so the
+// redirect can never lead to another site.
+func themeReturnPath(r *http.Request) string {
+	ref, err := url.Parse(r.Referer())
+	if err != nil || !strings.HasPrefix(ref.Path, "/") || strings.HasPrefix(ref.Path, "//") {
+		return "/"
+	}
+	back := url.URL{Path: ref.Path, RawQuery: ref.RawQuery}
+	return back.String()
+}
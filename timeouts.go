@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"time"
+)
+
+// Server timeouts, each read once at startup from the environment variable
+// named next to it as a Go duration such as 30s.
+var (
+	readTimeout    = resolveDuration("BLOG_READ_TIMEOUT", 10*time.Second)
+	writeTimeout   = resolveDuration("BLOG_WRITE_TIMEOUT", 30*time.Second)
+	idleTimeout    = resolveDuration("BLOG_IDLE_TIMEOUT", 120*time.Second)
+	handlerTimeout = resolveDuration("BLOG_HANDLER_TIMEOUT", 20*time.Second)
+)
+
+// timeoutMessage is the body of the 503 response sent when a handler runs
+// longer than handlerTimeout.
+const timeoutMessage = "<h1>Service Unavailable</h1><p>This page took too long to load. Please try again in a moment.</p>"
+
+// resolveDuration parses the environment variable name as a positive
+// duration, falling back to def when it is unset or invalid.
+func resolveDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		logWarn("Invalid %s %q, using %s", name, value, def)
+		return def
+	}
+	return d
+}
+
+// Timeout answers with 503 Service Unavailable when next takes longer than
+// handlerTimeout, so a slow render cannot hold the connection indefinitely.
+func Timeout(next http.Handler) http.Handler {
+	return http.TimeoutHandler(next, handlerTimeout, timeoutMessage)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// displayLocation is the time zone post dates are shown in, read from BLOG_TZ.
+var displayLocation = resolveLocation(os.Getenv("BLOG_TZ"))
+
+// resolveLocation loads the IANA time zone named by value, such as
+// Europe/Berlin. An empty value keeps the server's local time zone; an
+// unknown one logs a warning and falls back to UTC.
+func resolveLocation(value string) *time.Location {
+	if value == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		logWarn("Invalid BLOG_TZ %q, using UTC: %v", value, err)
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// defaultCertCache is the directory autocert stores certificates in when
+// BLOG_CERT_CACHE is unset.
+const defaultCertCache = "certs"
+
+// defaultHTTPAddr is the address of the HTTP-to-HTTPS redirect listener when
+// neither BLOG_HTTP_ADDR nor -http-addr is set.
+const defaultHTTPAddr = ":80"
+
+// tlsConfig holds how the server gets its certificate: from files, or from
+// Let's Encrypt through manager.
+type tlsConfig struct {
+	certFile, keyFile string
+	manager           *autocert.Manager
+}
+
+// loadTLS reads the TLS settings from the environment. BLOG_TLS_CERT and
+// BLOG_TLS_KEY name a certificate and key file. Without them, BLOG_DOMAINS
+// enables certificates from Let's Encrypt for the comma-separated domains.
+// It returns nil when TLS is not configured.
+func loadTLS() (*tlsConfig, error) {
+	cert, key := os.Getenv("BLOG_TLS_CERT"), os.Getenv("BLOG_TLS_KEY")
+	if cert != "" || key != "" {
+		if cert == "" || key == "" {
+			return nil, errors.New("BLOG_TLS_CERT and BLOG_TLS_KEY must be set together")
+		}
+		return &tlsConfig{certFile: cert, keyFile: key}, nil
+	}
+
+	if domains := os.Getenv("BLOG_DOMAINS"); domains != "" {
+		cacheDir := os.Getenv("BLOG_CERT_CACHE")
+		if cacheDir == "" {
+			cacheDir = defaultCertCache
+		}
+		return &tlsConfig{manager: &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(splitList(domains)...),
+			Cache:      autocert.DirCache(cacheDir),
+		}}, nil
+	}
+	return nil, nil
+}
+
+// String describes how the server is reached so it can be logged.
+func (t *tlsConfig) String() string {
+	switch {
+	case t == nil:
+		return "HTTP"
+	case t.manager != nil:
+		return "HTTPS with Let's Encrypt"
+	}
+	return "HTTPS with certificate files"
+}
+
+// listenAndServe starts server with TLS when t is set and in plain HTTP
+// otherwise.
+func (t *tlsConfig) listenAndServe(server *http.Server) error {
+	switch {
+	case t == nil:
+		return server.ListenAndServe()
+	case t.manager != nil:
+		server.TLSConfig = t.manager.TLSConfig()
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServeTLS(t.certFile, t.keyFile)
+}
+
+// redirectHandler returns the handler of the plain HTTP listener. It answers
+// Let's Encrypt HTTP challenges when certificates come from there, and
+// permanently redirects everything else to the HTTPS server at httpsAddr.
+func (t *tlsConfig) redirectHandler(httpsAddr string) http.Handler {
+	redirect := httpsRedirect(httpsAddr)
+	if t.manager != nil {
+		return t.manager.HTTPHandler(redirect)
+	}
+	return redirect
+}
+
+// httpsRedirect redirects requests to the same host and request URI over
+// HTTPS, on the port of httpsAddr unless it is the default port 443.
+func httpsRedirect(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			http.Error(w, "Missing Host header", http.StatusBadRequest)
+			return
+		}
+		if strings.Contains(host, ":") {
+			// Bracket IPv6 literals again after SplitHostPort removed them
+			host = "[" + strings.Trim(host, "[]") + "]"
+		}
+		if port != "" && port != "443" {
+			host += ":" + port
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
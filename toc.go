@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/yuin/goldmark/ast"
+)
+
+// Heading is an entry in a post's table of contents. Level 3 headings are
+// nested under the level 2 heading that precedes them.
+type Heading struct {
+	Text     string
+	Level    int
+	Anchor   string
+	Children []Heading
+}
+
+// tableOfContents collects the h2 and h3 headings of a parsed document. The
+// anchors are the ids assigned by the parser's auto heading ID option, which
+// slugifies the heading text and de-duplicates repeated headings.
+func tableOfContents(doc ast.Node, source []byte) []Heading {
+	var toc []Heading
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		if heading.Level != 2 && heading.Level != 3 {
+			return ast.WalkSkipChildren, nil
+		}
+
+		entry := Heading{
+			Text:  string(heading.Text(source)),
+			Level: heading.Level,
+		}
+		if id, ok := heading.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				entry.Anchor = string(b)
+			}
+		}
+
+		if entry.Level == 3 && len(toc) > 0 {
+			parent := &toc[len(toc)-1]
+			parent.Children = append(parent.Children, entry)
+		} else {
+			toc = append(toc, entry)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return toc
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Values of trailing_slash in the config file.
+const (
+	stripTrailingSlash = "strip" // /post/my-post/ redirects to /post/my-post
+	addTrailingSlash   = "add"   // /post/my-post redirects to /post/my-post/
+)
+
+// slashPath returns the canonical form of the URL path p under the
+// trailing_slash setting. The root path, paths under /static/, and paths of
+// files such as /feed.xml or /post/my-post.md never end in a slash, except
+// for "/" itself. Paths starting with "//" are left for the ServeMux to clean,
+// so they can never turn into a redirect to another host.
+func slashPath(p string) string {
+	if p == "/" || strings.HasPrefix(p, "//") || strings.HasPrefix(p, "/static/") {
+		return p
+	}
+	p = strings.TrimRight(p, "/")
+	if p == "" {
+		return "/"
+	}
+	if config.TrailingSlash == addTrailingSlash && path.Ext(p) == "" {
+		return p + "/"
+	}
+	return p
+}
+
+// TrailingSlash redirects GET and HEAD requests whose path differs from its
+// slashPath form with 301 Moved Permanently. In add mode the slash is
+// removed again before the request reaches next, so handlers only ever see
+// paths without one.
+func TrailingSlash(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if escaped := r.URL.EscapedPath(); slashPath(escaped) != escaped {
+			target := slashPath(escaped)
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+
+		if r.URL.Path != "/" && strings.HasSuffix(r.URL.Path, "/") && !strings.HasPrefix(r.URL.Path, "/static/") {
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path = strings.TrimSuffix(r.URL.Path, "/")
+			r2.URL.RawPath = ""
+			r = r2
+		}
+		next.ServeHTTP(w, r)
+	})
+}
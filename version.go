@@ -0,0 +1,32 @@
+package main
+
+import "net/http"
+
+// Build information, set at build time with
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildTime=$(date -u +%FT%TZ)"
+//
+// Each defaults to "dev" for local builds.
+var (
+	version   = "dev"
+	commit    = "dev"
+	buildTime = "dev"
+)
+
+// VersionHandler returns the build information as JSON.
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		Version   string `json:"version"`
+		Commit    string `json:"commit"`
+		BuildTime string `json:"build_time"`
+	}{version, commit, buildTime})
+}
+
+// VersionHeader adds an X-Blog-Version header naming the build to every
+// response of next.
+func VersionHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Blog-Version", version+" ("+commit+")")
+		next.ServeHTTP(w, r)
+	})
+}
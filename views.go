@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultViewsFile is where view counts are stored when BLOG_VIEWS_FILE is
+// unset.
+const defaultViewsFile = "views.json"
+
+// viewsFlushInterval is how often changed view counts are written to disk.
+const viewsFlushInterval = 30 * time.Second
+
+// ViewCounter counts post views in memory and persists them to a JSON file.
+// A nil *ViewCounter counts nothing, so the static site generator can render
+// posts without recording views.
+type ViewCounter struct {
+	mu     sync.Mutex
+	file   string
+	counts map[string]int
+	dirty  bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// views counts post views while the server runs. It stays nil otherwise.
+var views *ViewCounter
+
+// LoadViewCounter reads the counts stored in file. A missing file is not an
+// error and starts every count at zero.
+func LoadViewCounter(file string) (*ViewCounter, error) {
+	c := &ViewCounter{file: file, counts: make(map[string]int)}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.counts); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// viewKey returns the key a post's views are counted under. Slugs are only
+// unique within a section, so the section is part of it.
+func viewKey(post PostData) string {
+	return post.Section + "/" + post.Slug
+}
+
+// Add records a view of key and returns the new count.
+func (c *ViewCounter) Add(key string) int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[key]++
+	c.dirty = true
+	return c.counts[key]
+}
+
+// Counts returns a copy of every count.
+func (c *ViewCounter) Counts() map[string]int {
+	counts := make(map[string]int)
+	if c == nil {
+		return counts
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, n := range c.counts {
+		counts[key] = n
+	}
+	return counts
+}
+
+// Flush writes the counts to disk if they changed since the last flush. The
+// file is replaced atomically so a crash never leaves it half written.
+func (c *ViewCounter) Flush() error {
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(c.counts, "", "  ")
+	c.dirty = false
+	c.mu.Unlock()
+	if err == nil {
+		err = writeFileAtomic(c.file, data)
+	}
+	if err != nil {
+		// Try again on the next flush
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// writeFileAtomic writes data to a temporary file next to name and renames it
+// into place. The temporary file is named after name, so views.json is
+// written through .views-*.json.
+func writeFileAtomic(name string, data []byte) error {
+	ext := filepath.Ext(name)
+	pattern := "." + strings.TrimSuffix(filepath.Base(name), ext) + "-*" + ext
+	tmp, err := os.CreateTemp(filepath.Dir(name), pattern)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Start flushes the counts every interval until Close is called.
+func (c *ViewCounter) Start(interval time.Duration) {
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.Flush(); err != nil {
+					logError("Error saving view counts: %v", err)
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the periodic flush and writes any counts not saved yet.
+func (c *ViewCounter) Close() error {
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+	}
+	return c.Flush()
+}
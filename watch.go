@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watcher waits after the last event before
+// evicting, so the burst of events from a single editor save is handled once.
+const watchDebounce = 100 * time.Millisecond
+
+// watchOps are the events that make a cached post stale.
+const watchOps = fsnotify.Write | fsnotify.Create | fsnotify.Remove | fsnotify.Rename
+
+// contentDirs returns the directories watched for changes, relative to the
+// content directory.
+func contentDirs() []string {
+	return []string{postsSection.Dir, notesSection.Dir, "nav"}
+}
+
+// WatchContent evicts posts from cache as soon as their files under dirs of the
+// content directory change, instead of waiting for the ModTime check on the
+// next load to notice. Subdirectories present at startup are watched too, and
+// directories that do not exist are skipped. The returned function stops the watcher.
+func WatchContent(cache *PostCache, dirs []string) (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	root := contentDir
+	if root == "" {
+		root = "."
+	}
+	for _, dir := range dirs {
+		err := filepath.WalkDir(filepath.Join(root, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return watcher.Add(path)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	go watchLoop(watcher, cache, root)
+	return func() { watcher.Close() }, nil
+}
+
+// watchLoop collects changed files until no event has arrived for
+// watchDebounce, then evicts them by their path relative to root. It returns
+// once the watcher is closed.
+func watchLoop(watcher *fsnotify.Watcher, cache *PostCache, root string) {
+	pending := make(map[string]bool)
+	var flush <-chan time.Time
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&watchOps == 0 {
+				continue
+			}
+			file, err := filepath.Rel(root, event.Name)
+			if err != nil {
+				continue
+			}
+			pending[filepath.ToSlash(file)] = true
+			flush = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logError("Content watcher error: %v", err)
+		case <-flush:
+			for file := range pending {
+				cache.Evict(file)
+			}
+			contentVersion.Add(1)
+			pending = make(map[string]bool)
+			flush = nil
+		}
+	}
+}
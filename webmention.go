@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultMentionsFile is where received Webmentions are stored when
+// BLOG_WEBMENTIONS_FILE is unset.
+const defaultMentionsFile = "webmentions.json"
+
+// Limits on fetching the source of a Webmention.
+const (
+	mentionFetchTimeout = 10 * time.Second
+	mentionMaxBody      = 1 << 20
+)
+
+// maxMentionsPerPost caps the sources stored for one post, so the store and
+// the page listing them cannot grow without bound.
+const maxMentionsPerPost = 100
+
+// errTooManyMentions is returned by Add for a new source of a post that
+// already has maxMentionsPerPost mentions.
+var errTooManyMentions = errors.New("too many mentions of this post")
+
+// Mention is a verified Webmention: a page at Source linking to a post.
+type Mention struct {
+	Source   string    `json:"source"`
+	Received time.Time `json:"received"`
+}
+
+// MentionStore holds the accepted Webmentions of every post, keyed like view
+// counts, and saves them to a JSON file on each change. A nil *MentionStore
+// holds no mentions, so the static site generator can render posts without
+// one.
+type MentionStore struct {
+	mu       sync.Mutex
+	file     string
+	mentions map[string][]Mention
+}
+
+// mentions holds the received Webmentions while the server runs. It stays nil
+// otherwise.
+var mentions *MentionStore
+
+// LoadMentionStore reads the mentions stored in file. A missing file is not
+// an error and starts without mentions.
+func LoadMentionStore(file string) (*MentionStore, error) {
+	s := &MentionStore{file: file, mentions: make(map[string][]Mention)}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.mentions); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// For returns the mentions of the post stored under key, oldest first.
+func (s *MentionStore) For(key string) []Mention {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Mention(nil), s.mentions[key]...)
+}
+
+// Add stores a mention of the post under key and writes the file. A source
+// that already mentions the post only has its received time updated, as the
+// spec asks for repeated notifications. New sources beyond
+// maxMentionsPerPost are refused with errTooManyMentions.
+func (s *MentionStore) Add(key string, m Mention) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.mentions[key]
+	found := false
+	for i := range list {
+		if list[i].Source == m.Source {
+			list[i].Received = m.Received
+			found = true
+		}
+	}
+	if !found {
+		if len(list) >= maxMentionsPerPost {
+			return errTooManyMentions
+		}
+		list = append(list, m)
+	}
+	s.mentions[key] = list
+
+	data, err := json.MarshalIndent(s.mentions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.file, data)
+}
+
+// mentionTarget returns the published post that target, an absolute URL on
+// this site, points at. Both the post's own path and, with dated permalinks,
+// its /post/<slug> alias are accepted, with or without a trailing slash.
+func mentionTarget(target string) (PostData, bool) {
+	u, err := url.Parse(target)
+	base, baseErr := url.Parse(BaseURL())
+	if err != nil || baseErr != nil || u.Scheme != base.Scheme || !strings.EqualFold(u.Host, base.Host) {
+		return PostData{}, false
+	}
+	p := strings.TrimSuffix(u.Path, "/")
+
+	for _, section := range []Section{postsSection, notesSection} {
+		posts, err := LoadSectionPosts(section)
+		if err != nil {
+			return PostData{}, false
+		}
+		for _, post := range posts {
+			if p == post.rawPath() || p == section.Prefix()+post.Slug {
+				return post, true
+			}
+		}
+	}
+	return PostData{}, false
+}
+
+// publicDialer refuses connections to loopback, private, and link-local
+// addresses, so a Webmention cannot make the server fetch internal services.
+var publicDialer = &net.Dialer{
+	Timeout: mentionFetchTimeout,
+	Control: func(network, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+			ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+			return fmt.Errorf("address %s is not public", host)
+		}
+		return nil
+	},
+}
+
+// mentionClient fetches Webmention sources.
+var mentionClient = &http.Client{
+	Timeout:   mentionFetchTimeout,
+	Transport: &http.Transport{DialContext: publicDialer.DialContext},
+}
+
+// sourceLinksTo fetches source and reports whether its body contains a link
+// to target.
+func sourceLinksTo(ctx context.Context, source, target string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := mentionClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("source returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, mentionMaxBody))
+	if err != nil {
+		return false, err
+	}
+	page := string(body)
+	for _, quote := range []string{`"`, `'`} {
+		if strings.Contains(page, "href="+quote+target+quote) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL.
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// WebmentionHandler serves POST /webmention. It accepts a mention when target
+// is a published post on this site and the page at source links to it,
+// answering 400 Bad Request otherwise.
+func WebmentionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mentions == nil {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	source, target := r.PostFormValue("source"), r.PostFormValue("target")
+	if !isHTTPURL(source) || !isHTTPURL(target) {
+		http.Error(w, "source and target must be http or https URLs", http.StatusBadRequest)
+		return
+	}
+	if source == target {
+		http.Error(w, "source and target must differ", http.StatusBadRequest)
+		return
+	}
+	post, ok := mentionTarget(target)
+	if !ok {
+		http.Error(w, "target is not a post on this site", http.StatusBadRequest)
+		return
+	}
+
+	links, err := sourceLinksTo(r.Context(), source, target)
+	if err != nil {
+		logRequestError(r, fmt.Errorf("fetching webmention source %s: %w", source, err))
+		http.Error(w, "source could not be fetched", http.StatusBadRequest)
+		return
+	}
+	if !links {
+		http.Error(w, "source does not link to target", http.StatusBadRequest)
+		return
+	}
+
+	err = mentions.Add(viewKey(post), Mention{Source: source, Received: time.Now().UTC()})
+	if errors.Is(err, errTooManyMentions) {
+		http.Error(w, "target has too many mentions", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logRequestError(r, err)
+		http.Error(w, "Error storing webmention", http.StatusInternalServerError)
+		return
+	}
+	logInfo("Accepted webmention of %s from %s", target, source)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "Webmention accepted")
+}
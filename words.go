@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"unicode"
+)
+
+// wordsPerMinute is the reading speed used for reading-time estimates.
+const wordsPerMinute = 200
+
+// stripCodeBlocks returns the Markdown source without its fenced code blocks.
+func stripCodeBlocks(src []byte) string {
+	var out strings.Builder
+	var fence string
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")) {
+			fence = trimmed[:3]
+			continue
+		}
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+// wordCount returns the number of whitespace-separated words in a Markdown
+// body. Code blocks are not counted.
+func wordCount(src []byte) int {
+	return len(strings.Fields(stripCodeBlocks(src)))
+}
+
+// charCount returns the number of characters in a Markdown body, not counting
+// whitespace or code blocks.
+func charCount(src []byte) int {
+	n := 0
+	for _, r := range stripCodeBlocks(src) {
+		if !unicode.IsSpace(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// readingTime estimates the minutes needed to read a Markdown body, rounding
+// up. Code blocks are not counted.
+func readingTime(src []byte) int {
+	return (wordCount(src) + wordsPerMinute - 1) / wordsPerMinute
+}